@@ -0,0 +1,90 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+)
+
+// defaultArchiveName is the archive file created in the output directory when
+// no -archive path is given
+const defaultArchiveName = ".skool-loom-dl-archive"
+
+// downloadArchive records the videos that finished downloading as
+// "<provider> <id>" lines. This matches yt-dlp's --download-archive format,
+// whose extractor keys are the provider names, so either tool can read the
+// file. Entries are appended one line at a time and synced to disk, so the
+// archive stays usable when a run is interrupted. It is safe for concurrent use.
+type downloadArchive struct {
+	mu           sync.Mutex
+	path         string
+	keys         map[string]bool
+	needsNewline bool
+}
+
+// openArchive loads the archive at path, treating a missing file as empty.
+// Malformed lines, such as one cut short by a crash, are ignored.
+func openArchive(path string) (*downloadArchive, error) {
+	archive := &downloadArchive{path: path, keys: make(map[string]bool)}
+
+	content, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return archive, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading download archive: %v", err)
+	}
+
+	for _, line := range strings.Split(string(content), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 2 {
+			archive.keys[strings.Join(fields, " ")] = true
+		}
+	}
+	archive.needsNewline = len(content) > 0 && !bytes.HasSuffix(content, []byte("\n"))
+
+	return archive, nil
+}
+
+// Has reports whether the video was downloaded by an earlier run
+func (a *downloadArchive) Has(video VideoRef) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.keys[video.key()]
+}
+
+// Add records a finished download
+func (a *downloadArchive) Add(video VideoRef) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	key := video.key()
+	if a.keys[key] {
+		return nil
+	}
+
+	f, err := os.OpenFile(a.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("error opening download archive: %v", err)
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+
+	line := key + "\n"
+	if a.needsNewline {
+		line = "\n" + line
+	}
+	if _, err := f.WriteString(line); err != nil {
+		return fmt.Errorf("error writing download archive: %v", err)
+	}
+	if err := f.Sync(); err != nil {
+		return fmt.Errorf("error writing download archive: %v", err)
+	}
+
+	a.keys[key] = true
+	a.needsNewline = false
+	return nil
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// loomRef returns a reference to the Loom video with the given ID
+func loomRef(id string) VideoRef {
+	return VideoRef{Provider: providerLoom, VideoID: id}
+}
+
+func TestOpenArchive_Missing(t *testing.T) {
+	archive, err := openArchive(filepath.Join(t.TempDir(), "archive"))
+	if err != nil {
+		t.Fatalf("openArchive() error = %v", err)
+	}
+	if archive.Has(loomRef("abc123")) {
+		t.Error("Expected empty archive")
+	}
+}
+
+func TestDownloadArchive_AddAndReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "archive")
+
+	archive, err := openArchive(path)
+	if err != nil {
+		t.Fatalf("openArchive() error = %v", err)
+	}
+	if err := archive.Add(loomRef("abc123")); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	if err := archive.Add(loomRef("def456")); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	if err := archive.Add(loomRef("abc123")); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read archive: %v", err)
+	}
+	if string(content) != "loom abc123\nloom def456\n" {
+		t.Errorf("Unexpected archive content %q", content)
+	}
+
+	reloaded, err := openArchive(path)
+	if err != nil {
+		t.Fatalf("openArchive() error = %v", err)
+	}
+	if !reloaded.Has(loomRef("abc123")) || !reloaded.Has(loomRef("def456")) {
+		t.Error("Expected reloaded archive to contain both videos")
+	}
+	if reloaded.Has(loomRef("ghi789")) {
+		t.Error("Expected reloaded archive not to contain unknown video")
+	}
+}
+
+func TestOpenArchive_TruncatedLine(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "archive")
+	if err := os.WriteFile(path, []byte("loom abc123\nyoutube xyz\nloom"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	archive, err := openArchive(path)
+	if err != nil {
+		t.Fatalf("openArchive() error = %v", err)
+	}
+	if !archive.Has(loomRef("abc123")) {
+		t.Error("Expected archive to contain abc123")
+	}
+	if !archive.Has(VideoRef{Provider: providerYouTube, VideoID: "xyz"}) || archive.Has(loomRef("xyz")) {
+		t.Error("Expected entries to be kept per provider")
+	}
+
+	if err := archive.Add(loomRef("def456")); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+
+	reloaded, err := openArchive(path)
+	if err != nil {
+		t.Fatalf("openArchive() error = %v", err)
+	}
+	if !reloaded.Has(loomRef("def456")) {
+		t.Error("Expected entry after truncated line to be readable")
+	}
+}
@@ -0,0 +1,509 @@
+package main
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/sha1"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/network"
+	_ "modernc.org/sqlite"
+)
+
+// Browsers accepted by -cookies-from-browser
+const (
+	browserFirefox  = "firefox"
+	browserChrome   = "chrome"
+	browserChromium = "chromium"
+)
+
+// chromeEpochOffset is the number of seconds between 1601-01-01, the epoch of
+// Chrome's cookie timestamps, and the Unix epoch
+const chromeEpochOffset = 11644473600
+
+// chromeHashPrefixVersion is the cookie database version from which Chrome
+// prefixes each decrypted value with the SHA-256 hash of the cookie's domain
+const chromeHashPrefixVersion = 24
+
+// chromeKeyringPassword returns the password Chrome stored in the desktop
+// keyring on Linux, or "" when there is none. It is a variable so tests can
+// run without a keyring.
+var chromeKeyringPassword = func(browser string) string {
+	out, err := exec.Command("secret-tool", "lookup", "application", browser).Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// parseBrowserSpec splits a -cookies-from-browser value such as
+// "chrome:Profile 1" into the browser and the profile
+func parseBrowserSpec(spec string) (browser, profile string, err error) {
+	browser, profile, _ = strings.Cut(spec, ":")
+	browser = strings.ToLower(strings.TrimSpace(browser))
+	switch browser {
+	case browserFirefox, browserChrome, browserChromium:
+		return browser, profile, nil
+	}
+	return "", "", fmt.Errorf("unsupported browser %q, use firefox, chrome or chromium", browser)
+}
+
+// loadBrowserCookies reads the Skool and Loom cookies from the cookie
+// database of a local browser profile
+func loadBrowserCookies(spec string) ([]*network.CookieParam, error) {
+	browser, profile, err := parseBrowserSpec(spec)
+	if err != nil {
+		return nil, err
+	}
+
+	var dbPath string
+	if browser == browserFirefox {
+		dbPath, err = findFirefoxCookies(profile)
+	} else {
+		dbPath, err = findChromeCookies(browser, profile)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var cookies []*network.CookieParam
+	if browser == browserFirefox {
+		cookies, err = readFirefoxCookies(dbPath)
+	} else {
+		cookies, err = readChromeCookies(dbPath, newChromeDecryptor(browser))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading %s cookies from %s: %v", browser, dbPath, err)
+	}
+	return cookies, nil
+}
+
+// firefoxProfilesDir returns the directory holding the Firefox profiles
+func firefoxProfilesDir() (string, error) {
+	switch runtime.GOOS {
+	case "windows":
+		return filepath.Join(os.Getenv("APPDATA"), "Mozilla", "Firefox", "Profiles"), nil
+	case "darwin":
+		dir, err := os.UserConfigDir()
+		return filepath.Join(dir, "Firefox", "Profiles"), err
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	dir := filepath.Join(home, ".mozilla", "firefox")
+	if _, err := os.Stat(dir); err != nil {
+		// Firefox installed as a snap keeps its profiles elsewhere
+		if snap := filepath.Join(home, "snap", "firefox", "common", ".mozilla", "firefox"); isDir(snap) {
+			return snap, nil
+		}
+	}
+	return dir, nil
+}
+
+// findFirefoxCookies returns the cookie database of the named Firefox
+// profile, which may be given as a path, as the profile directory name or by
+// the name after its random prefix, e.g. "default-release". Without a name,
+// the profile whose cookies changed last is used.
+func findFirefoxCookies(profile string) (string, error) {
+	if profile != "" && isDir(profile) {
+		return existingFile(filepath.Join(profile, "cookies.sqlite"))
+	}
+
+	root, err := firefoxProfilesDir()
+	if err != nil {
+		return "", err
+	}
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		return "", fmt.Errorf("no Firefox profiles found: %v", err)
+	}
+
+	var best string
+	var bestTime time.Time
+	for _, entry := range entries {
+		name := entry.Name()
+		if !entry.IsDir() || (profile != "" && name != profile && !strings.HasSuffix(name, "."+profile)) {
+			continue
+		}
+		info, err := os.Stat(filepath.Join(root, name, "cookies.sqlite"))
+		if err != nil {
+			continue
+		}
+		if best == "" || info.ModTime().After(bestTime) {
+			best, bestTime = filepath.Join(root, name, "cookies.sqlite"), info.ModTime()
+		}
+	}
+	if best == "" {
+		if profile != "" {
+			return "", fmt.Errorf("no Firefox profile %q with cookies in %s", profile, root)
+		}
+		return "", fmt.Errorf("no Firefox profile with cookies in %s", root)
+	}
+	return best, nil
+}
+
+// chromeUserDataDir returns the directory holding the profiles of Chrome or
+// Chromium
+func chromeUserDataDir(browser string) (string, error) {
+	if runtime.GOOS == "windows" {
+		if browser == browserChromium {
+			return filepath.Join(os.Getenv("LOCALAPPDATA"), "Chromium", "User Data"), nil
+		}
+		return filepath.Join(os.Getenv("LOCALAPPDATA"), "Google", "Chrome", "User Data"), nil
+	}
+
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	switch {
+	case runtime.GOOS == "darwin" && browser == browserChromium:
+		return filepath.Join(dir, "Chromium"), nil
+	case runtime.GOOS == "darwin":
+		return filepath.Join(dir, "Google", "Chrome"), nil
+	case browser == browserChromium:
+		return filepath.Join(dir, "chromium"), nil
+	}
+	return filepath.Join(dir, "google-chrome"), nil
+}
+
+// findChromeCookies returns the cookie database of a Chrome profile, given as
+// a path or as the profile directory name such as "Profile 1". Without a
+// name, the Default profile is used.
+func findChromeCookies(browser, profile string) (string, error) {
+	dir := profile
+	if !isDir(dir) {
+		root, err := chromeUserDataDir(browser)
+		if err != nil {
+			return "", err
+		}
+		if profile == "" {
+			profile = "Default"
+		}
+		dir = filepath.Join(root, profile)
+	}
+
+	// Newer versions keep the cookies in the Network subdirectory
+	for _, path := range []string{filepath.Join(dir, "Network", "Cookies"), filepath.Join(dir, "Cookies")} {
+		if _, err := os.Stat(path); err == nil {
+			return path, nil
+		}
+	}
+	return "", fmt.Errorf("no %s cookies found in %s", browser, dir)
+}
+
+// readFirefoxCookies reads the Skool and Loom cookies of a Firefox cookie
+// database
+func readFirefoxCookies(dbPath string) ([]*network.CookieParam, error) {
+	db, cleanup, err := openCookieDatabase(dbPath)
+	if err != nil {
+		return nil, err
+	}
+	defer cleanup()
+
+	rows, err := db.Query(`SELECT host, name, value, path, expiry, isSecure, isHttpOnly, sameSite
+		FROM moz_cookies WHERE host LIKE '%skool.com' OR host LIKE '%loom.com'`)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	var cookies []*network.CookieParam
+	for rows.Next() {
+		var host, name, value, path string
+		var expiry int64
+		var secure, httpOnly bool
+		var sameSite int
+		if err := rows.Scan(&host, &name, &value, &path, &expiry, &secure, &httpOnly, &sameSite); err != nil {
+			return nil, err
+		}
+		if cookieSite(host) == "" {
+			continue
+		}
+
+		cookie := &network.CookieParam{
+			Domain:   strings.TrimPrefix(host, "."),
+			Name:     name,
+			Value:    value,
+			Path:     path,
+			Secure:   secure,
+			HTTPOnly: httpOnly,
+			SameSite: browserSameSite(sameSite),
+		}
+		// Recent Firefox versions store the expiry in milliseconds
+		if expiry > 1e11 {
+			expiry /= 1000
+		}
+		if expiry > 0 {
+			t := cdp.TimeSinceEpoch(time.Unix(expiry, 0))
+			cookie.Expires = &t
+		}
+		cookies = append(cookies, cookie)
+	}
+	return cookies, rows.Err()
+}
+
+// readChromeCookies reads and decrypts the Skool and Loom cookies of a
+// Chrome cookie database
+func readChromeCookies(dbPath string, decryptor *chromeDecryptor) ([]*network.CookieParam, error) {
+	db, cleanup, err := openCookieDatabase(dbPath)
+	if err != nil {
+		return nil, err
+	}
+	defer cleanup()
+
+	var version string
+	if err := db.QueryRow(`SELECT value FROM meta WHERE key = 'version'`).Scan(&version); err == nil {
+		v, _ := strconv.Atoi(version)
+		decryptor.hashPrefix = v >= chromeHashPrefixVersion
+	}
+
+	rows, err := db.Query(`SELECT host_key, name, value, encrypted_value, path, expires_utc, is_secure, is_httponly, samesite
+		FROM cookies WHERE host_key LIKE '%skool.com' OR host_key LIKE '%loom.com'`)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	var cookies []*network.CookieParam
+	for rows.Next() {
+		var host, name, value, path string
+		var encrypted []byte
+		var expires int64
+		var secure, httpOnly bool
+		var sameSite int
+		if err := rows.Scan(&host, &name, &value, &encrypted, &path, &expires, &secure, &httpOnly, &sameSite); err != nil {
+			return nil, err
+		}
+		if cookieSite(host) == "" {
+			continue
+		}
+
+		if value == "" && len(encrypted) > 0 {
+			if value, err = decryptor.decrypt(encrypted); err != nil {
+				return nil, fmt.Errorf("cookie %s for %s: %v", name, host, err)
+			}
+		}
+
+		cookie := &network.CookieParam{
+			Domain:   strings.TrimPrefix(host, "."),
+			Name:     name,
+			Value:    value,
+			Path:     path,
+			Secure:   secure,
+			HTTPOnly: httpOnly,
+			SameSite: browserSameSite(sameSite),
+		}
+		if expires > 0 {
+			t := cdp.TimeSinceEpoch(time.Unix(expires/1e6-chromeEpochOffset, 0))
+			cookie.Expires = &t
+		}
+		cookies = append(cookies, cookie)
+	}
+	return cookies, rows.Err()
+}
+
+// browserSameSite maps the SameSite values both browsers store to cookie
+// parameters. None is left unset like in the cookie files, since setting it
+// on a cookie that is not secure makes the browser reject the cookie.
+func browserSameSite(value int) network.CookieSameSite {
+	switch value {
+	case 1:
+		return network.CookieSameSiteLax
+	case 2:
+		return network.CookieSameSiteStrict
+	}
+	return ""
+}
+
+// openCookieDatabase opens a copy of a browser's cookie database, since the
+// running browser keeps the original locked. The returned cleanup function
+// closes the database and removes the copy.
+func openCookieDatabase(dbPath string) (*sql.DB, func(), error) {
+	dir, err := os.MkdirTemp("", "skool-loom-dl-browser-*")
+	if err != nil {
+		return nil, nil, err
+	}
+	removeDir := func() {
+		_ = os.RemoveAll(dir)
+	}
+
+	target := filepath.Join(dir, "cookies.sqlite")
+	if err := copyFile(dbPath, target); err != nil {
+		removeDir()
+		return nil, nil, err
+	}
+	// Recent changes may still be in the write-ahead log
+	if err := copyFile(dbPath+"-wal", target+"-wal"); err != nil && !errors.Is(err, os.ErrNotExist) {
+		removeDir()
+		return nil, nil, err
+	}
+
+	db, err := sql.Open("sqlite", target)
+	if err != nil {
+		removeDir()
+		return nil, nil, err
+	}
+	return db, func() {
+		_ = db.Close()
+		removeDir()
+	}, nil
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = in.Close()
+	}()
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		_ = out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+func isDir(path string) bool {
+	info, err := os.Stat(path)
+	return path != "" && err == nil && info.IsDir()
+}
+
+func existingFile(path string) (string, error) {
+	if _, err := os.Stat(path); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// chromeDecryptor decrypts the cookie values Chrome encrypts on Linux. Values
+// prefixed with v10 use a key derived from the fixed password "peanuts".
+// Values prefixed with v11 use a key derived from a password kept in the
+// desktop keyring, and fall back to the fixed and the empty password when
+// the keyring is not available.
+type chromeDecryptor struct {
+	v10Keys    [][]byte
+	v11Keys    [][]byte
+	hashPrefix bool // the value starts with a hash of the cookie domain
+}
+
+func newChromeDecryptor(browser string) *chromeDecryptor {
+	peanuts := chromeKey("peanuts")
+	d := &chromeDecryptor{v10Keys: [][]byte{peanuts}}
+	if password := chromeKeyringPassword(browser); password != "" {
+		d.v11Keys = append(d.v11Keys, chromeKey(password))
+	}
+	d.v11Keys = append(d.v11Keys, peanuts, chromeKey(""))
+	return d
+}
+
+// chromeKey derives the AES key Chrome uses on Linux from a password
+func chromeKey(password string) []byte {
+	key, _ := pbkdf2.Key(sha1.New, password, []byte("saltysalt"), 1, 16)
+	return key
+}
+
+func (d *chromeDecryptor) decrypt(encrypted []byte) (string, error) {
+	if runtime.GOOS != "linux" {
+		return "", fmt.Errorf("decrypting Chrome cookies is only supported on Linux, use Firefox or a cookies file")
+	}
+
+	var keys [][]byte
+	switch {
+	case bytes.HasPrefix(encrypted, []byte("v10")):
+		keys = d.v10Keys
+	case bytes.HasPrefix(encrypted, []byte("v11")):
+		keys = d.v11Keys
+	default:
+		return "", fmt.Errorf("unknown encryption version %q", encrypted[:min(3, len(encrypted))])
+	}
+
+	for _, key := range keys {
+		if value, ok := d.decryptWithKey(encrypted[3:], key); ok {
+			return value, nil
+		}
+	}
+	return "", fmt.Errorf("could not decrypt the cookie value, is the browser keyring unlocked?")
+}
+
+// decryptWithKey decrypts an AES-CBC encrypted value and reports whether the
+// key was right, judged by the padding and the value being text
+func (d *chromeDecryptor) decryptWithKey(ciphertext, key []byte) (string, bool) {
+	if len(ciphertext) == 0 || len(ciphertext)%aes.BlockSize != 0 {
+		return "", false
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return "", false
+	}
+	plain := make([]byte, len(ciphertext))
+	iv := bytes.Repeat([]byte{' '}, aes.BlockSize)
+	cipher.NewCBCDecrypter(block, iv).CryptBlocks(plain, ciphertext)
+
+	padding := int(plain[len(plain)-1])
+	if padding == 0 || padding > aes.BlockSize || padding > len(plain) {
+		return "", false
+	}
+	for _, b := range plain[len(plain)-padding:] {
+		if int(b) != padding {
+			return "", false
+		}
+	}
+	plain = plain[:len(plain)-padding]
+
+	if d.hashPrefix {
+		if len(plain) < 32 {
+			return "", false
+		}
+		plain = plain[32:]
+	}
+	if !utf8.Valid(plain) {
+		return "", false
+	}
+	return string(plain), true
+}
+
+// cookieParamsToJSON converts cookies to the JSON cookie file format
+func cookieParamsToJSON(cookies []*network.CookieParam) []JSONCookie {
+	result := make([]JSONCookie, 0, len(cookies))
+	for _, c := range cookies {
+		cookie := JSONCookie{Host: c.Domain, Name: c.Name, Value: c.Value, Path: c.Path}
+		if c.Expires != nil {
+			cookie.Expiry = c.Expires.Time().Unix()
+		}
+		if c.Secure {
+			cookie.IsSecure = 1
+		}
+		if c.HTTPOnly {
+			cookie.IsHttpOnly = 1
+		}
+		cookie.SameSite = jsonSameSite(c.SameSite)
+		result = append(result, cookie)
+	}
+	return result
+}
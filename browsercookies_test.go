@@ -0,0 +1,249 @@
+package main
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/sha256"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+)
+
+// createCookieDB creates an SQLite database at path from SQL statements
+func createCookieDB(t *testing.T, path string, statements ...string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer func() {
+		_ = db.Close()
+	}()
+	for _, statement := range statements {
+		if _, err := db.Exec(statement); err != nil {
+			t.Fatalf("Failed to run %q: %v", statement, err)
+		}
+	}
+}
+
+// encryptChromeValue encrypts a cookie value the way Chrome does on Linux
+func encryptChromeValue(t *testing.T, version, password, host, value string, hashPrefix bool) []byte {
+	t.Helper()
+	plain := []byte(value)
+	if hashPrefix {
+		sum := sha256.Sum256([]byte(host))
+		plain = append(sum[:], plain...)
+	}
+	padding := aes.BlockSize - len(plain)%aes.BlockSize
+	plain = append(plain, bytes.Repeat([]byte{byte(padding)}, padding)...)
+
+	block, err := aes.NewCipher(chromeKey(password))
+	if err != nil {
+		t.Fatalf("Failed to create cipher: %v", err)
+	}
+	encrypted := make([]byte, len(plain))
+	cipher.NewCBCEncrypter(block, bytes.Repeat([]byte{' '}, aes.BlockSize)).CryptBlocks(encrypted, plain)
+	return append([]byte(version), encrypted...)
+}
+
+func TestParseBrowserSpec(t *testing.T) {
+	tests := []struct {
+		spec        string
+		wantBrowser string
+		wantProfile string
+		wantErr     bool
+	}{
+		{spec: "firefox", wantBrowser: browserFirefox},
+		{spec: "Chrome:Profile 1", wantBrowser: browserChrome, wantProfile: "Profile 1"},
+		{spec: "chromium:/home/me/.config/chromium/Default", wantBrowser: browserChromium, wantProfile: "/home/me/.config/chromium/Default"},
+		{spec: "safari", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			browser, profile, err := parseBrowserSpec(tt.spec)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseBrowserSpec() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if browser != tt.wantBrowser || profile != tt.wantProfile {
+				t.Errorf("parseBrowserSpec() = %q, %q, want %q, %q", browser, profile, tt.wantBrowser, tt.wantProfile)
+			}
+		})
+	}
+}
+
+func TestReadFirefoxCookies(t *testing.T) {
+	profile := filepath.Join(t.TempDir(), "abcd1234.default-release")
+	createCookieDB(t, filepath.Join(profile, "cookies.sqlite"),
+		`CREATE TABLE moz_cookies (id INTEGER PRIMARY KEY, host TEXT, name TEXT, value TEXT, path TEXT,
+			expiry INTEGER, isSecure INTEGER, isHttpOnly INTEGER, sameSite INTEGER)`,
+		`INSERT INTO moz_cookies (host, name, value, path, expiry, isSecure, isHttpOnly, sameSite) VALUES
+			('.skool.com', 'auth_token', 'skool-token', '/', 1900000000, 1, 1, 1),
+			('www.loom.com', 'connect.sid', 'loom-session', '/', 1900000000000, 1, 0, 0),
+			('.notskool.com', 'other', 'x', '/', 0, 0, 0, 0),
+			('.example.com', 'tracking', 'y', '/', 0, 0, 0, 0)`,
+	)
+
+	path, err := findFirefoxCookies(profile)
+	if err != nil {
+		t.Fatalf("findFirefoxCookies() error = %v", err)
+	}
+	cookies, err := readFirefoxCookies(path)
+	if err != nil {
+		t.Fatalf("readFirefoxCookies() error = %v", err)
+	}
+	if len(cookies) != 2 {
+		t.Fatalf("readFirefoxCookies() returned %d cookies, want 2: %+v", len(cookies), cookies)
+	}
+
+	auth := cookies[0]
+	if auth.Domain != "skool.com" || auth.Name != "auth_token" || auth.Value != "skool-token" || !auth.Secure || !auth.HTTPOnly {
+		t.Errorf("auth cookie = %+v", auth)
+	}
+	if auth.Expires == nil || auth.Expires.Time().Unix() != 1900000000 {
+		t.Errorf("auth cookie expires = %v, want 1900000000", auth.Expires)
+	}
+	// Expiries in milliseconds are converted to seconds
+	if loom := cookies[1]; loom.Expires == nil || loom.Expires.Time().Unix() != 1900000000 {
+		t.Errorf("loom cookie expires = %v, want 1900000000", loom.Expires)
+	}
+}
+
+func TestFindFirefoxCookies_ProfileName(t *testing.T) {
+	root := t.TempDir()
+	t.Setenv("HOME", root)
+	t.Setenv("APPDATA", root)
+	t.Setenv("XDG_CONFIG_HOME", root)
+	profilesDir, err := firefoxProfilesDir()
+	if err != nil {
+		t.Fatalf("firefoxProfilesDir() error = %v", err)
+	}
+
+	for _, name := range []string{"aaaa.default", "bbbb.default-release"} {
+		createCookieDB(t, filepath.Join(profilesDir, name, "cookies.sqlite"), `CREATE TABLE moz_cookies (id INTEGER)`)
+	}
+	old := time.Now().Add(-time.Hour)
+	if err := os.Chtimes(filepath.Join(profilesDir, "aaaa.default", "cookies.sqlite"), old, old); err != nil {
+		t.Fatalf("Failed to set file time: %v", err)
+	}
+
+	tests := []struct {
+		profile string
+		want    string
+		wantErr bool
+	}{
+		{profile: "", want: "bbbb.default-release"},
+		{profile: "default", want: "aaaa.default"},
+		{profile: "aaaa.default", want: "aaaa.default"},
+		{profile: "work", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.profile, func(t *testing.T) {
+			path, err := findFirefoxCookies(tt.profile)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("findFirefoxCookies() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && filepath.Base(filepath.Dir(path)) != tt.want {
+				t.Errorf("findFirefoxCookies() = %s, want profile %s", path, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadChromeCookies(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("Chrome cookies are only decrypted on Linux")
+	}
+
+	tests := []struct {
+		name       string
+		dbVersion  string
+		hashPrefix bool
+		keyring    string
+		version    string
+		password   string
+	}{
+		{name: "v10 with peanuts", dbVersion: "20", version: "v10", password: "peanuts"},
+		{name: "v10 with domain hash", dbVersion: "24", hashPrefix: true, version: "v10", password: "peanuts"},
+		{name: "v11 with keyring", dbVersion: "24", hashPrefix: true, keyring: "secret", version: "v11", password: "secret"},
+		{name: "v11 without keyring", dbVersion: "20", version: "v11", password: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keyring := chromeKeyringPassword
+			chromeKeyringPassword = func(string) string { return tt.keyring }
+			defer func() {
+				chromeKeyringPassword = keyring
+			}()
+
+			profile := filepath.Join(t.TempDir(), "Default")
+			dbPath := filepath.Join(profile, "Network", "Cookies")
+			createCookieDB(t, dbPath,
+				`CREATE TABLE meta (key TEXT, value TEXT)`,
+				`INSERT INTO meta VALUES ('version', '`+tt.dbVersion+`')`,
+				`CREATE TABLE cookies (host_key TEXT, name TEXT, value TEXT, encrypted_value BLOB, path TEXT,
+					expires_utc INTEGER, is_secure INTEGER, is_httponly INTEGER, samesite INTEGER)`,
+			)
+			db, err := sql.Open("sqlite", dbPath)
+			if err != nil {
+				t.Fatalf("Failed to open database: %v", err)
+			}
+			encrypted := encryptChromeValue(t, tt.version, tt.password, ".skool.com", "skool-token", tt.hashPrefix)
+			expires := (int64(1900000000) + chromeEpochOffset) * 1e6
+			_, err = db.Exec(`INSERT INTO cookies VALUES
+				('.skool.com', 'auth_token', '', ?, '/', ?, 1, 1, 2),
+				('www.loom.com', 'plain', 'loom-value', x'', '/', 0, 0, 0, -1),
+				('.example.com', 'tracking', 'x', x'', '/', 0, 0, 0, -1)`, encrypted, expires)
+			_ = db.Close()
+			if err != nil {
+				t.Fatalf("Failed to insert cookies: %v", err)
+			}
+
+			path, err := findChromeCookies(browserChrome, profile)
+			if err != nil {
+				t.Fatalf("findChromeCookies() error = %v", err)
+			}
+			cookies, err := readChromeCookies(path, newChromeDecryptor(browserChrome))
+			if err != nil {
+				t.Fatalf("readChromeCookies() error = %v", err)
+			}
+			if len(cookies) != 2 {
+				t.Fatalf("readChromeCookies() returned %d cookies, want 2", len(cookies))
+			}
+			auth := cookies[0]
+			if auth.Value != "skool-token" || auth.Domain != "skool.com" || !auth.Secure || !auth.HTTPOnly {
+				t.Errorf("auth cookie = %+v, want decrypted skool-token", auth)
+			}
+			if auth.Expires == nil || auth.Expires.Time().Unix() != 1900000000 {
+				t.Errorf("auth cookie expires = %v, want 1900000000", auth.Expires)
+			}
+			if cookies[1].Value != "loom-value" || cookies[1].Expires != nil {
+				t.Errorf("loom cookie = %+v, want the plain session cookie", cookies[1])
+			}
+		})
+	}
+}
+
+func TestChromeDecryptor_WrongKey(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("Chrome cookies are only decrypted on Linux")
+	}
+	keyring := chromeKeyringPassword
+	chromeKeyringPassword = func(string) string { return "" }
+	defer func() {
+		chromeKeyringPassword = keyring
+	}()
+
+	encrypted := encryptChromeValue(t, "v11", "locked keyring", ".skool.com", "skool-token", false)
+	if value, err := newChromeDecryptor(browserChrome).decrypt(encrypted); err == nil {
+		t.Errorf("decrypt() = %q, want an error without the keyring password", value)
+	}
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	configDirName         = "skool-loom-dl"
+	configFileName        = "config.yaml"
+	defaultConfigPathHint = "~/.config/" + configDirName + "/" + configFileName
+	envPrefix             = "SKOOL_LOOM_DL_"
+)
+
+// configFile is the layout of the config file. Options are named like the
+// flags they set. The options under defaults apply to every profile, and a
+// profile's own options take precedence over them.
+//
+//	default_profile: school
+//	defaults:
+//	  output: ~/Videos/skool
+//	profiles:
+//	  school:
+//	    url: https://www.skool.com/school/classroom
+//	    cookies: school-cookies.json
+//	    wait: 5
+type configFile struct {
+	DefaultProfile string                    `yaml:"default_profile"`
+	Defaults       map[string]any            `yaml:"defaults"`
+	Profiles       map[string]map[string]any `yaml:"profiles"`
+}
+
+// defaultConfigPath returns the config file looked for when -config is not
+// given, e.g. ~/.config/skool-loom-dl/config.yaml on Linux
+func defaultConfigPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, configDirName, configFileName)
+}
+
+// envName returns the environment variable that overrides a flag, e.g.
+// SKOOL_LOOM_DL_RETRY_DELAY for -retry-delay
+func envName(flagName string) string {
+	return envPrefix + strings.ToUpper(strings.ReplaceAll(flagName, "-", "_"))
+}
+
+// applyConfigSources fills in the flags that were not given on the command
+// line from the selected profile of the config file, then lets environment
+// variables override any flag. The config file and profile themselves can
+// also be chosen through the environment.
+func applyConfigSources(flags *flag.FlagSet, path, profile string, getenv func(string) string) error {
+	if v := getenv(envName("config")); v != "" {
+		path = v
+	}
+	if v := getenv(envName("profile")); v != "" {
+		profile = v
+	}
+
+	required := path != ""
+	if !required {
+		path = defaultConfigPath()
+	}
+	values, err := loadProfile(path, profile, required)
+	if err != nil {
+		return err
+	}
+
+	onCommandLine := make(map[string]bool)
+	flags.Visit(func(f *flag.Flag) {
+		onCommandLine[f.Name] = true
+	})
+
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	for _, name := range names {
+		if name == "config" || name == "profile" || flags.Lookup(name) == nil {
+			return fmt.Errorf("config file %s: unknown option %q", path, name)
+		}
+		if onCommandLine[name] {
+			continue
+		}
+		if err := flags.Set(name, expandHome(values[name])); err != nil {
+			return fmt.Errorf("config file %s: option %q: %v", path, name, err)
+		}
+	}
+
+	var envErr error
+	flags.VisitAll(func(f *flag.Flag) {
+		if v := getenv(envName(f.Name)); v != "" && envErr == nil {
+			if err := flags.Set(f.Name, v); err != nil {
+				envErr = fmt.Errorf("%s: %v", envName(f.Name), err)
+			}
+		}
+	})
+	return envErr
+}
+
+// loadProfile reads the config file at path and returns the options of the
+// named profile, or of the default profile when name is empty, merged over
+// the shared defaults. A missing file is only an error when it was asked for
+// explicitly or a profile was requested.
+func loadProfile(path, name string, required bool) (map[string]string, error) {
+	content, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) && !required && name == "" {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading config file: %v", err)
+	}
+
+	var file configFile
+	decoder := yaml.NewDecoder(bytes.NewReader(content))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&file); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("error parsing config file %s: %v", path, err)
+	}
+
+	if name == "" {
+		name = file.DefaultProfile
+	}
+	values := make(map[string]string)
+	if err := mergeOptions(values, file.Defaults); err != nil {
+		return nil, fmt.Errorf("config file %s: %v", path, err)
+	}
+	if name == "" {
+		return values, nil
+	}
+
+	profile, ok := file.Profiles[name]
+	if !ok {
+		available := make([]string, 0, len(file.Profiles))
+		for p := range file.Profiles {
+			available = append(available, p)
+		}
+		slices.Sort(available)
+		return nil, fmt.Errorf("config file %s has no profile %q (available: %s)", path, name, strings.Join(available, ", "))
+	}
+	if err := mergeOptions(values, profile); err != nil {
+		return nil, fmt.Errorf("config file %s: profile %q: %v", path, name, err)
+	}
+	return values, nil
+}
+
+// mergeOptions adds the scalar options to values in the form flag.Set expects
+func mergeOptions(values map[string]string, options map[string]any) error {
+	for name, value := range options {
+		switch v := value.(type) {
+		case string, bool, int, float64:
+			values[name] = fmt.Sprint(v)
+		case nil:
+			values[name] = ""
+		default:
+			return fmt.Errorf("option %q must be a single value", name)
+		}
+	}
+	return nil
+}
+
+// expandHome replaces a leading ~/ with the home directory, since paths in
+// the config file are not expanded by a shell
+func expandHome(value string) string {
+	if !strings.HasPrefix(value, "~/") {
+		return value
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return value
+	}
+	return filepath.Join(home, value[2:])
+}
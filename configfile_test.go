@@ -0,0 +1,155 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testConfigFile = `default_profile: alpha
+defaults:
+  output: shared-downloads
+  wait: 4
+profiles:
+  alpha:
+    url: https://www.skool.com/alpha/classroom
+    cookies: alpha-cookies.json
+    concurrency: 2
+  beta:
+    url: https://www.skool.com/beta/classroom
+    email: beta@example.com
+    wait: 8
+    headless: false
+    retry-delay: 5s
+`
+
+func writeTestConfig(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+	return path
+}
+
+// testEnv returns a getenv function backed by a map
+func testEnv(vars map[string]string) func(string) string {
+	return func(name string) string {
+		return vars[name]
+	}
+}
+
+func TestParseFlags_ConfigFile(t *testing.T) {
+	path := writeTestConfig(t, testConfigFile)
+
+	tests := []struct {
+		name   string
+		args   []string
+		env    map[string]string
+		verify func(t *testing.T, config Config)
+	}{
+		{
+			name: "default profile",
+			args: []string{"-config", path},
+			verify: func(t *testing.T, config Config) {
+				if config.SkoolURL != "https://www.skool.com/alpha/classroom" || config.CookiesFile != "alpha-cookies.json" {
+					t.Errorf("Expected alpha profile, got %+v", config)
+				}
+				if config.OutputDir != "shared-downloads" || config.WaitTime != 4 || config.Concurrency != 2 {
+					t.Errorf("Expected shared defaults and profile values, got %+v", config)
+				}
+			},
+		},
+		{
+			name: "selected profile overrides defaults",
+			args: []string{"-config", path, "-profile", "beta"},
+			verify: func(t *testing.T, config Config) {
+				if config.SkoolURL != "https://www.skool.com/beta/classroom" || config.Email != "beta@example.com" {
+					t.Errorf("Expected beta profile, got %+v", config)
+				}
+				if config.WaitTime != 8 || config.Headless || config.RetryDelay != 5*time.Second {
+					t.Errorf("Expected beta options, got wait %d, headless %v, retry delay %v", config.WaitTime, config.Headless, config.RetryDelay)
+				}
+			},
+		},
+		{
+			name: "flags override the file",
+			args: []string{"-config", path, "-profile", "beta", "-wait", "1", "-output", "mine"},
+			verify: func(t *testing.T, config Config) {
+				if config.WaitTime != 1 || config.OutputDir != "mine" {
+					t.Errorf("Expected flag values, got wait %d, output %q", config.WaitTime, config.OutputDir)
+				}
+			},
+		},
+		{
+			name: "environment overrides flags and file",
+			args: []string{"-config", path, "-wait", "1"},
+			env:  map[string]string{"SKOOL_LOOM_DL_WAIT": "9", "SKOOL_LOOM_DL_PROFILE": "beta", "SKOOL_LOOM_DL_PASSWORD": "secret"},
+			verify: func(t *testing.T, config Config) {
+				if config.WaitTime != 9 {
+					t.Errorf("Expected wait from environment, got %d", config.WaitTime)
+				}
+				if config.Email != "beta@example.com" || config.Password != "secret" {
+					t.Errorf("Expected beta profile with password from environment, got %+v", config)
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config, err := parseFlags(tt.args, testEnv(tt.env))
+			if err != nil {
+				t.Fatalf("parseFlags() error = %v", err)
+			}
+			tt.verify(t, config)
+		})
+	}
+}
+
+func TestParseFlags_ConfigFileErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		args    []string
+		wantErr string
+	}{
+		{name: "unknown profile", content: testConfigFile, args: []string{"-profile", "gamma"}, wantErr: "available: alpha, beta"},
+		{name: "unknown option", content: "defaults:\n  speed: 3\n", wantErr: `unknown option "speed"`},
+		{name: "invalid value", content: "defaults:\n  wait: soon\n", wantErr: `option "wait"`},
+		{name: "list value", content: "defaults:\n  url: [a, b]\n", wantErr: "single value"},
+		{name: "unknown section", content: "profile:\n  a: {}\n", wantErr: "error parsing config file"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args := append([]string{"-config", writeTestConfig(t, tt.content)}, tt.args...)
+			_, err := parseFlags(args, testEnv(nil))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestParseFlags_MissingConfigFile(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	config, err := parseFlags([]string{"-url", "https://www.skool.com/school/classroom"}, testEnv(nil))
+	if err != nil {
+		t.Fatalf("Expected missing default config file to be ignored, got %v", err)
+	}
+	if config.OutputDir != defaultOutputDir || config.WaitTime != defaultWaitTime {
+		t.Errorf("Expected built-in defaults, got %+v", config)
+	}
+
+	if _, err := parseFlags([]string{"-profile", "school"}, testEnv(nil)); err == nil {
+		t.Error("Expected error for a profile without config file, got nil")
+	}
+	if _, err := parseFlags([]string{"-config", filepath.Join(t.TempDir(), "missing.yaml")}, testEnv(nil)); err == nil {
+		t.Error("Expected error for a missing explicit config file, got nil")
+	}
+}
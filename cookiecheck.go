@@ -0,0 +1,197 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/chromedp/cdproto/network"
+)
+
+// cookieExpiryWarning is how close to its expiry a cookie is reported as
+// expiring soon
+const cookieExpiryWarning = 7 * 24 * time.Hour
+
+// skoolAuthCookie is the cookie that holds the Skool login
+const skoolAuthCookie = "auth_token"
+
+// Cookie states reported by -check-cookies
+const (
+	cookieValid    = "valid"
+	cookieSession  = "session" // no expiry, valid until the browser closes
+	cookieExpiring = "expiring"
+	cookieExpired  = "expired"
+)
+
+// cookieInfo describes one Skool or Loom cookie of a cookies file
+type cookieInfo struct {
+	Site    string // "skool" or "loom"
+	Name    string
+	Domain  string
+	Expires time.Time // zero for session cookies
+	State   string
+}
+
+// cookieSite returns the site a cookie domain belongs to, or "" for cookies
+// of other sites
+func cookieSite(domain string) string {
+	domain = strings.TrimPrefix(domain, ".")
+	switch {
+	case domain == "skool.com" || strings.HasSuffix(domain, ".skool.com"):
+		return "skool"
+	case domain == "loom.com" || strings.HasSuffix(domain, ".loom.com"):
+		return "loom"
+	}
+	return ""
+}
+
+// inspectCookies returns the state of the Skool and Loom cookies at now
+func inspectCookies(cookies []*network.CookieParam, now time.Time) []cookieInfo {
+	var result []cookieInfo
+	for _, c := range cookies {
+		site := cookieSite(c.Domain)
+		if site == "" {
+			continue
+		}
+
+		info := cookieInfo{Site: site, Name: c.Name, Domain: c.Domain, State: cookieSession}
+		if c.Expires != nil {
+			info.Expires = c.Expires.Time()
+			switch {
+			case !info.Expires.After(now):
+				info.State = cookieExpired
+			case info.Expires.Sub(now) < cookieExpiryWarning:
+				info.State = cookieExpiring
+			default:
+				info.State = cookieValid
+			}
+		}
+		result = append(result, info)
+	}
+	return result
+}
+
+// hasUsableAuthCookie reports whether a Skool auth cookie has not expired
+func hasUsableAuthCookie(infos []cookieInfo) bool {
+	for _, info := range infos {
+		if info.Site == "skool" && info.Name == skoolAuthCookie && info.State != cookieExpired {
+			return true
+		}
+	}
+	return false
+}
+
+// skoolCookieHeader builds a Cookie header from the unexpired Skool cookies
+func skoolCookieHeader(cookies []*network.CookieParam, now time.Time) string {
+	var parts []string
+	for _, c := range cookies {
+		if cookieSite(c.Domain) != "skool" || (c.Expires != nil && !c.Expires.Time().After(now)) {
+			continue
+		}
+		parts = append(parts, c.Name+"="+c.Value)
+	}
+	return strings.Join(parts, "; ")
+}
+
+// verifySkoolSession loads targetURL with the cookie header and fails with
+// errAuthFailed when Skool answers as it does for visitors who are not
+// logged in: by refusing the request or redirecting to the login or public
+// about page.
+func verifySkoolSession(ctx context.Context, client *http.Client, targetURL, cookieHeader string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, targetURL, nil)
+	if err != nil {
+		return permanent(err)
+	}
+	req.Header.Set("User-Agent", nativeUserAgent)
+	req.Header.Set("Accept", "text/html,application/xhtml+xml,application/xml")
+	req.Header.Set("Cookie", cookieHeader)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<20))
+
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		return permanent(fmt.Errorf("%w: %s answered with status %d", errAuthFailed, targetURL, resp.StatusCode))
+	case resp.StatusCode < 200 || resp.StatusCode > 299:
+		return &httpStatusError{URL: targetURL, StatusCode: resp.StatusCode}
+	}
+
+	final := resp.Request.URL
+	requested, _ := url.Parse(targetURL)
+	if strings.Contains(final.Path, "/login") {
+		return permanent(fmt.Errorf("%w: redirected to the login page", errAuthFailed))
+	}
+	if strings.Contains(final.Path, "/about") && (requested == nil || !strings.Contains(requested.Path, "/about")) {
+		return errPublicPage
+	}
+	return nil
+}
+
+// checkCookies reports the Skool and Loom cookies of the cookies file, then
+// confirms with a single request that Skool accepts them, and returns the
+// exit code
+func checkCookies(ctx context.Context, config Config) int {
+	cookies, err := parseCookiesFile(config.CookiesFile)
+	if err != nil {
+		slog.Error("❌ Error parsing cookies", "path", config.CookiesFile, "error", err)
+		return exitError
+	}
+
+	infos := inspectCookies(cookies, time.Now())
+	if len(infos) == 0 {
+		slog.Error("❌ No Skool or Loom cookies found", "path", config.CookiesFile)
+		return exitAuthFailed
+	}
+	for _, info := range infos {
+		attrs := []any{"site", info.Site, "name", info.Name, "domain", info.Domain, "state", info.State}
+		if !info.Expires.IsZero() {
+			attrs = append(attrs, "expires", info.Expires.Format(time.RFC3339))
+		}
+		switch info.State {
+		case cookieExpired:
+			slog.Warn("⚠️ Cookie expired", attrs...)
+		case cookieExpiring:
+			slog.Warn("⚠️ Cookie expires soon", attrs...)
+		default:
+			slog.Info("🍪 Cookie", attrs...)
+		}
+	}
+
+	if !hasUsableAuthCookie(infos) {
+		slog.Error("❌ No valid Skool login cookie found, export your cookies again", "cookie", skoolAuthCookie)
+		return exitAuthFailed
+	}
+
+	targetURL := config.SkoolURL
+	if targetURL == "" {
+		targetURL = skoolBaseURL
+	}
+	slog.Info("🔍 Checking session", "url", targetURL)
+
+	client := &http.Client{Timeout: httpTimeout}
+	header := skoolCookieHeader(cookies, time.Now())
+	err = retry(ctx, config.retryPolicy(), "Checking session", slog.Default(), func() error {
+		return verifySkoolSession(ctx, client, targetURL, header)
+	})
+	if err != nil {
+		slog.Error("❌ Session check failed", "error", err)
+		if ctx.Err() != nil {
+			return exitInterrupted
+		}
+		return exitCodeForError(err)
+	}
+
+	slog.Info("✅ Cookies are valid")
+	return exitOK
+}
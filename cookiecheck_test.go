@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/network"
+)
+
+func expiresAt(t time.Time) *cdp.TimeSinceEpoch {
+	e := cdp.TimeSinceEpoch(t)
+	return &e
+}
+
+func TestInspectCookies(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	cookies := []*network.CookieParam{
+		{Name: "auth_token", Domain: "skool.com", Expires: expiresAt(now.Add(30 * 24 * time.Hour))},
+		{Name: "client_id", Domain: "www.skool.com", Expires: expiresAt(now.Add(time.Hour))},
+		{Name: "old", Domain: "skool.com", Expires: expiresAt(now.Add(-time.Hour))},
+		{Name: "connect.sid", Domain: "www.loom.com"},
+		{Name: "tracking", Domain: "example.com"},
+	}
+
+	infos := inspectCookies(cookies, now)
+	want := []struct{ site, name, state string }{
+		{"skool", "auth_token", cookieValid},
+		{"skool", "client_id", cookieExpiring},
+		{"skool", "old", cookieExpired},
+		{"loom", "connect.sid", cookieSession},
+	}
+	if len(infos) != len(want) {
+		t.Fatalf("inspectCookies() returned %d cookies, want %d: %+v", len(infos), len(want), infos)
+	}
+	for i, w := range want {
+		if infos[i].Site != w.site || infos[i].Name != w.name || infos[i].State != w.state {
+			t.Errorf("cookie %d = %s %s %s, want %s %s %s", i, infos[i].Site, infos[i].Name, infos[i].State, w.site, w.name, w.state)
+		}
+	}
+	if !hasUsableAuthCookie(infos) {
+		t.Error("hasUsableAuthCookie() = false, want true")
+	}
+	if hasUsableAuthCookie(infos[2:]) {
+		t.Error("hasUsableAuthCookie() = true without an auth_token, want false")
+	}
+
+	header := skoolCookieHeader(cookies, now)
+	if header != "auth_token=; client_id=" {
+		t.Errorf("skoolCookieHeader() = %q, want only the unexpired Skool cookies", header)
+	}
+}
+
+func TestVerifySkoolSession(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Cookie") != "auth_token=abc" && r.URL.Path != "/login" {
+			http.Redirect(w, r, "/login", http.StatusFound)
+			return
+		}
+		switch r.URL.Path {
+		case "/private/classroom":
+			http.Redirect(w, r, "/private/about", http.StatusFound)
+		case "/forbidden":
+			w.WriteHeader(http.StatusForbidden)
+		case "/unavailable":
+			w.WriteHeader(http.StatusServiceUnavailable)
+		default:
+			_, _ = w.Write([]byte("<html></html>"))
+		}
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name        string
+		path        string
+		cookie      string
+		wantAuthErr bool
+		wantRetry   bool
+	}{
+		{name: "Accepted", path: "/school/classroom", cookie: "auth_token=abc"},
+		{name: "About page requested", path: "/school/about", cookie: "auth_token=abc"},
+		{name: "Redirected to login", path: "/school/classroom", cookie: "auth_token=expired", wantAuthErr: true},
+		{name: "Redirected to about page", path: "/private/classroom", cookie: "auth_token=abc", wantAuthErr: true},
+		{name: "Forbidden", path: "/forbidden", cookie: "auth_token=abc", wantAuthErr: true},
+		{name: "Server error", path: "/unavailable", cookie: "auth_token=abc", wantRetry: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := verifySkoolSession(context.Background(), server.Client(), server.URL+tt.path, tt.cookie)
+			switch {
+			case tt.wantAuthErr:
+				if !errors.Is(err, errAuthFailed) || isRetryable(err) {
+					t.Errorf("verifySkoolSession() error = %v, want a permanent authentication error", err)
+				}
+			case tt.wantRetry:
+				if err == nil || !isRetryable(err) {
+					t.Errorf("verifySkoolSession() error = %v, want a retryable error", err)
+				}
+			case err != nil:
+				t.Errorf("verifySkoolSession() error = %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,505 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/chromedp/chromedp"
+)
+
+const (
+	maxExpandPasses = 10
+	expandWaitTime  = 500 * time.Millisecond
+)
+
+// Course crawl statuses reported per course when crawling a community
+const (
+	courseStatusOK     = "ok"
+	courseStatusLocked = "locked"
+	courseStatusEmpty  = "empty"
+	courseStatusFailed = "failed"
+)
+
+// errCourseLocked is returned when Skool refuses to open a course for the
+// current account, typically because it is locked or not purchased
+var errCourseLocked = errors.New("course is locked or not purchased")
+
+// errNoLessons is returned when a course sidebar lists no lessons
+var errNoLessons = errors.New("no lessons found in course sidebar")
+
+// Lesson is a single classroom lesson discovered while crawling a course.
+// ModuleIndex and Index are 1-based positions in the course sidebar; Index
+// counts lessons within their module.
+type Lesson struct {
+	Community   string
+	Course      string
+	Module      string
+	ModuleIndex int
+	Title       string
+	Index       int
+	URL         string
+	Videos      []VideoRef
+}
+
+// CourseResult is the outcome of crawling one course of a community
+type CourseResult struct {
+	Title   string
+	URL     string
+	Status  string
+	Err     error
+	Lessons []Lesson
+}
+
+// courseCard is a course entry as read from the classroom index
+type courseCard struct {
+	Title string `json:"title"`
+	URL   string `json:"url"`
+}
+
+// lessonLink is a lesson entry as read from the course sidebar
+type lessonLink struct {
+	Module string `json:"module"`
+	Title  string `json:"title"`
+	URL    string `json:"url"`
+}
+
+// expandSidebarJS clicks every collapsed section that has not been clicked yet
+// and returns how many were expanded. Links and the page header are left alone
+// so we never navigate away or open account menus.
+const expandSidebarJS = `(() => {
+	const toggles = Array.from(document.querySelectorAll('[aria-expanded="false"]'))
+		.filter(el => !el.closest('header') && !el.matches('a[href]') && !el.dataset.sldExpanded);
+	toggles.forEach(el => { el.dataset.sldExpanded = '1'; el.click(); });
+	return toggles.length;
+})()`
+
+// lessonLinksJS walks section toggles and lesson links in document order so each
+// lesson can be attributed to the module heading that precedes it
+const lessonLinksJS = `(() => {
+	const firstLine = el => (el.innerText || el.textContent || '').trim().split('\n')[0].trim();
+	const result = [];
+	let module = '';
+	document.querySelectorAll('[aria-expanded], a[href*="md="]').forEach(el => {
+		if (el.matches('a[href*="md="]')) {
+			result.push({module: module, title: firstLine(el), url: el.href});
+		} else if (!el.closest('header')) {
+			module = firstLine(el);
+		}
+	});
+	return result;
+})()`
+
+// courseTitleJS reads the course name from the first heading outside the page header
+const courseTitleJS = `(() => {
+	const heading = Array.from(document.querySelectorAll('h1, h2')).find(el => !el.closest('header'));
+	return heading ? (heading.innerText || '').trim().split('\n')[0].trim() : '';
+})()`
+
+// courseCardsJS returns every link to a course on the classroom index along
+// with the title of its card
+const courseCardsJS = `(() => {
+	const parts = location.pathname.split('/').filter(Boolean);
+	const prefix = '/' + parts[0] + '/classroom/';
+	const result = [];
+	document.querySelectorAll('a[href*="/classroom/"]').forEach(a => {
+		const path = new URL(a.href).pathname;
+		if (!path.startsWith(prefix) || path.slice(prefix.length).includes('/')) {
+			return;
+		}
+		const card = a.closest('[class*="Card"], li, article') || a;
+		const text = (card.innerText || card.textContent || '').trim();
+		const heading = card.querySelector('h1, h2, h3, h4, [class*="Title"]');
+		const title = ((heading && heading.innerText) || text).trim().split('\n')[0].trim();
+		result.push({title: title, url: a.href});
+	});
+	return result;
+})()`
+
+// classroomPagesJS returns the links to further pages of the classroom index
+const classroomPagesJS = `Array.from(document.querySelectorAll('a[href*="classroom?p="]')).map(a => a.href)`
+
+// crawlCommunity enumerates every course on a community classroom index and
+// crawls the lessons of each course that the account can open
+func crawlCommunity(ctx context.Context, indexURL string, waitTime int, policy RetryPolicy) ([]CourseResult, error) {
+	slog.Info("🏫 Navigating to classroom index", "url", indexURL)
+	currentURL, err := navigate(ctx, indexURL, waitTime, policy)
+	if err != nil {
+		return nil, fmt.Errorf("failed to navigate to classroom index: %v", err)
+	}
+
+	slog.Info("📍 Landed on", "url", currentURL)
+	if strings.Contains(currentURL, "/about") {
+		return nil, errPublicPage
+	}
+
+	cards, err := discoverCourses(ctx, waitTime, policy)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read classroom index: %v", err)
+	}
+	if len(cards) == 0 {
+		return nil, fmt.Errorf("no courses found on classroom index")
+	}
+
+	slog.Info("📚 Found courses", "count", len(cards))
+
+	var results []CourseResult
+	for i, card := range cards {
+		slog.Info("🎓 Crawling course", "course", card.Title, "progress", fmt.Sprintf("%d/%d", i+1, len(cards)))
+
+		// Locked courses are told apart by Skool redirecting away from them,
+		// since card texts such as "Unlock your potential" are ambiguous
+		result := CourseResult{Title: card.Title, URL: card.URL}
+		lessons, err := crawlCourse(ctx, card.URL, card.Title, waitTime, policy)
+		switch {
+		case errors.Is(err, errCourseLocked):
+			slog.Info("🔒 Course is locked, skipping", "course", card.Title)
+			result.Status = courseStatusLocked
+		case errors.Is(err, errNoLessons):
+			slog.Warn("⚠️ Course has no lessons", "course", card.Title)
+			result.Status = courseStatusEmpty
+		case err != nil:
+			slog.Error("❌ Error crawling course", "course", card.Title, "error", err)
+			result.Status = courseStatusFailed
+			result.Err = err
+		default:
+			result.Status = courseStatusOK
+			result.Lessons = lessons
+		}
+		results = append(results, result)
+	}
+
+	return results, nil
+}
+
+// discoverCourses collects the course cards from every page of the classroom index
+func discoverCourses(ctx context.Context, waitTime int, policy RetryPolicy) ([]courseCard, error) {
+	var cards, pageCards []courseCard
+	var pages []string
+	if err := runWithTimeout(ctx,
+		chromedp.Evaluate(courseCardsJS, &pageCards),
+		chromedp.Evaluate(classroomPagesJS, &pages),
+	); err != nil {
+		return nil, err
+	}
+	cards = append(cards, pageCards...)
+
+	visited := make(map[string]bool)
+	for _, page := range pages {
+		if visited[page] {
+			continue
+		}
+		visited[page] = true
+
+		if _, err := navigate(ctx, page, waitTime, policy); err != nil {
+			return nil, err
+		}
+		pageCards = nil
+		if err := runWithTimeout(ctx, chromedp.Evaluate(courseCardsJS, &pageCards)); err != nil {
+			return nil, err
+		}
+		cards = append(cards, pageCards...)
+	}
+
+	return filterCourseCards(cards), nil
+}
+
+// crawlCourse visits every lesson of the course containing startURL and
+// collects the videos embedded in each one. The course title is read from
+// the page when courseTitle is empty.
+func crawlCourse(ctx context.Context, startURL, courseTitle string, waitTime int, policy RetryPolicy) ([]Lesson, error) {
+	courseURL, err := courseRootURL(startURL)
+	if err != nil {
+		return nil, err
+	}
+
+	slog.Info("🏫 Navigating to course", "url", courseURL)
+	currentURL, err := navigate(ctx, courseURL, waitTime, policy)
+	if err != nil {
+		return nil, fmt.Errorf("failed to navigate to course: %v", err)
+	}
+
+	slog.Info("📍 Landed on", "url", currentURL)
+	if strings.Contains(currentURL, "/about") {
+		return nil, errPublicPage
+	}
+	if !samePath(currentURL, courseURL) {
+		return nil, errCourseLocked
+	}
+
+	links, err := discoverLessons(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read course sidebar: %v", err)
+	}
+
+	links = filterLessonLinks(links, courseURL)
+	if len(links) == 0 {
+		return nil, errNoLessons
+	}
+
+	if courseTitle == "" {
+		if err := runWithTimeout(ctx, chromedp.Evaluate(courseTitleJS, &courseTitle)); err != nil || courseTitle == "" {
+			courseTitle = path.Base(strings.TrimSuffix(urlPath(courseURL), "/"))
+		}
+	}
+
+	slog.Info("📚 Found lessons", "course", courseTitle, "count", len(links))
+
+	lessons := buildLessons(links, communitySlug(courseURL), courseTitle)
+	for i := range lessons {
+		lesson := &lessons[i]
+		slog.Info("📖 Reading lesson", "lesson", lessonLabel(links[i]), "progress", fmt.Sprintf("%d/%d", i+1, len(lessons)))
+
+		videos, err := lessonPageVideos(ctx, lesson.URL, waitTime, policy)
+		if err != nil {
+			slog.Error("❌ Error reading lesson", "lesson", lessonLabel(links[i]), "error", err)
+			continue
+		}
+
+		lesson.Videos = videos
+		for j := range lesson.Videos {
+			lesson.attach(&lesson.Videos[j])
+		}
+		slog.Info("🎬 Found videos", "count", len(lesson.Videos))
+	}
+
+	return lessons, nil
+}
+
+// lessonPageVideos loads a lesson page and collects its videos
+func lessonPageVideos(ctx context.Context, lessonURL string, waitTime int, policy RetryPolicy) ([]VideoRef, error) {
+	traffic, stop := watchPageTraffic(ctx)
+	defer stop()
+	if _, err := navigate(ctx, lessonURL, waitTime, policy); err != nil {
+		return nil, fmt.Errorf("failed to load lesson: %w", err)
+	}
+	return pageVideos(ctx, lessonURL, traffic)
+}
+
+// buildLessons turns sidebar links into lessons numbered in sidebar order.
+// Modules are numbered by first appearance and lessons within their module.
+func buildLessons(links []lessonLink, community, course string) []Lesson {
+	moduleIndex := make(map[string]int)
+	lessonCount := make(map[string]int)
+
+	lessons := make([]Lesson, 0, len(links))
+	for _, link := range links {
+		if link.Module != "" && moduleIndex[link.Module] == 0 {
+			moduleIndex[link.Module] = len(moduleIndex) + 1
+		}
+		lessonCount[link.Module]++
+
+		lessons = append(lessons, Lesson{
+			Community:   community,
+			Course:      course,
+			Module:      link.Module,
+			ModuleIndex: moduleIndex[link.Module],
+			Title:       link.Title,
+			Index:       lessonCount[link.Module],
+			URL:         link.URL,
+		})
+	}
+	return lessons
+}
+
+// discoverLessons expands every collapsed sidebar section and returns the
+// lesson links in the order they appear
+func discoverLessons(ctx context.Context) ([]lessonLink, error) {
+	for pass := 0; pass < maxExpandPasses; pass++ {
+		var expanded int
+		if err := runWithTimeout(ctx, chromedp.Evaluate(expandSidebarJS, &expanded)); err != nil {
+			return nil, err
+		}
+		if expanded == 0 {
+			break
+		}
+		if err := runWithTimeout(ctx, chromedp.Sleep(expandWaitTime)); err != nil {
+			return nil, err
+		}
+	}
+
+	var links []lessonLink
+	if err := runWithTimeout(ctx, chromedp.Evaluate(lessonLinksJS, &links)); err != nil {
+		return nil, err
+	}
+	return links, nil
+}
+
+// isClassroomIndex reports whether raw points at a community's classroom
+// index (https://www.skool.com/<community>/classroom) rather than a course
+func isClassroomIndex(raw string) bool {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return false
+	}
+	parts := strings.Split(strings.Trim(u.Path, "/"), "/")
+	return len(parts) == 2 && parts[1] == "classroom"
+}
+
+// filterCourseCards drops cards without a URL and duplicate links to the same course
+func filterCourseCards(cards []courseCard) []courseCard {
+	seen := make(map[string]bool)
+	var result []courseCard
+	for _, card := range cards {
+		u, err := url.Parse(card.URL)
+		if err != nil || u.Path == "" || seen[u.Path] {
+			continue
+		}
+		seen[u.Path] = true
+
+		card.Title = strings.TrimSpace(card.Title)
+		if card.Title == "" {
+			card.Title = u.Path
+		}
+		result = append(result, card)
+	}
+	return result
+}
+
+// communitySlug returns the community part of a Skool URL path
+func communitySlug(raw string) string {
+	parts := strings.Split(strings.Trim(urlPath(raw), "/"), "/")
+	return parts[0]
+}
+
+func urlPath(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return ""
+	}
+	return u.Path
+}
+
+func samePath(a, b string) bool {
+	ua, errA := url.Parse(a)
+	ub, errB := url.Parse(b)
+	if errA != nil || errB != nil {
+		return false
+	}
+	return strings.TrimSuffix(ua.Path, "/") == strings.TrimSuffix(ub.Path, "/")
+}
+
+// courseRootURL strips the lesson selector from a classroom URL
+func courseRootURL(raw string) (string, error) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return "", fmt.Errorf("invalid course URL: %v", err)
+	}
+	if !strings.Contains(u.Path, "/classroom/") {
+		return "", fmt.Errorf("not a classroom course URL: %s", raw)
+	}
+
+	q := u.Query()
+	q.Del("md")
+	u.RawQuery = q.Encode()
+	u.Fragment = ""
+	return u.String(), nil
+}
+
+// filterLessonLinks keeps links that belong to the given course and drops
+// duplicates, preserving sidebar order
+func filterLessonLinks(links []lessonLink, courseURL string) []lessonLink {
+	course, err := url.Parse(courseURL)
+	if err != nil {
+		return nil
+	}
+
+	seen := make(map[string]bool)
+	var result []lessonLink
+	for _, link := range links {
+		u, err := url.Parse(link.URL)
+		if err != nil || u.Path != course.Path {
+			continue
+		}
+
+		id := u.Query().Get("md")
+		if id == "" || seen[id] {
+			continue
+		}
+		seen[id] = true
+
+		link.Title = strings.TrimSpace(link.Title)
+		link.Module = strings.TrimSpace(link.Module)
+		result = append(result, link)
+	}
+
+	return result
+}
+
+func lessonLabel(link lessonLink) string {
+	title := link.Title
+	if title == "" {
+		title = link.URL
+	}
+	if link.Module == "" {
+		return title
+	}
+	return link.Module + " › " + title
+}
+
+// attach records the lesson's position in the course on a video found in it
+func (l Lesson) attach(video *VideoRef) {
+	video.Community = l.Community
+	video.Course = l.Course
+	video.Module = l.Module
+	video.ModuleIndex = l.ModuleIndex
+	video.LessonTitle = l.Title
+	video.LessonIndex = l.Index
+}
+
+// lessonVideos flattens the lessons into a list of unique videos in sidebar
+// order. A video embedded in several lessons is kept with the first one.
+func lessonVideos(lessons []Lesson) []VideoRef {
+	var videos []VideoRef
+	for _, lesson := range lessons {
+		videos = append(videos, lesson.Videos...)
+	}
+	return uniqueVideos(videos)
+}
+
+// printLessonReport logs every video found with the lesson it came from
+func printLessonReport(lessons []Lesson) {
+	for _, lesson := range lessons {
+		label := lessonLabel(lessonLink{Module: lesson.Module, Title: lesson.Title, URL: lesson.URL})
+		for _, video := range lesson.Videos {
+			slog.Info("📋 Lesson video", "lesson", label, "url", video.URL)
+		}
+	}
+}
+
+// courseLessons collects the lessons of every successfully crawled course
+func courseLessons(results []CourseResult) []Lesson {
+	var lessons []Lesson
+	for _, result := range results {
+		lessons = append(lessons, result.Lessons...)
+	}
+	return lessons
+}
+
+func printCourseReport(results []CourseResult) {
+	for _, result := range results {
+		icon := "✅"
+		switch result.Status {
+		case courseStatusLocked:
+			icon = "🔒"
+		case courseStatusEmpty:
+			icon = "⚠️"
+		case courseStatusFailed:
+			icon = "❌"
+		}
+
+		attrs := []any{"course", result.Title, "status", result.Status}
+		if result.Status == courseStatusOK {
+			attrs = append(attrs, "lessons", len(result.Lessons), "videos", len(lessonVideos(result.Lessons)))
+		}
+		if result.Err != nil {
+			attrs = append(attrs, "error", result.Err)
+		}
+		slog.Info(icon+" Course", attrs...)
+	}
+}
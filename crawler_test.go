@@ -0,0 +1,246 @@
+package main
+
+import (
+	"bytes"
+	"log/slog"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCourseRootURL(t *testing.T) {
+	tests := []struct {
+		name      string
+		input     string
+		expected  string
+		shouldErr bool
+	}{
+		{
+			name:     "Course URL",
+			input:    "https://www.skool.com/school/classroom/abc123",
+			expected: "https://www.skool.com/school/classroom/abc123",
+		},
+		{
+			name:     "Lesson URL",
+			input:    "https://www.skool.com/school/classroom/abc123?md=lesson1",
+			expected: "https://www.skool.com/school/classroom/abc123",
+		},
+		{
+			name:     "Lesson URL with fragment",
+			input:    "https://www.skool.com/school/classroom/abc123?md=lesson1#top",
+			expected: "https://www.skool.com/school/classroom/abc123",
+		},
+		{
+			name:      "Not a classroom URL",
+			input:     "https://www.skool.com/school/about",
+			shouldErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := courseRootURL(tt.input)
+			if tt.shouldErr {
+				if err == nil {
+					t.Errorf("courseRootURL(%q) expected error, got nil", tt.input)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("courseRootURL(%q) unexpected error: %v", tt.input, err)
+			}
+			if result != tt.expected {
+				t.Errorf("courseRootURL(%q) = %q, want %q", tt.input, result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestFilterLessonLinks(t *testing.T) {
+	courseURL := "https://www.skool.com/school/classroom/abc123"
+	links := []lessonLink{
+		{Module: " Intro ", Title: " Welcome ", URL: courseURL + "?md=l1"},
+		{Module: "Intro", Title: "Welcome again", URL: courseURL + "?md=l1"},
+		{Module: "Intro", Title: "Other course", URL: "https://www.skool.com/school/classroom/other?md=x"},
+		{Module: "Intro", Title: "No lesson id", URL: courseURL},
+		{Module: "Advanced", Title: "Deep dive", URL: courseURL + "?md=l2"},
+	}
+
+	expected := []lessonLink{
+		{Module: "Intro", Title: "Welcome", URL: courseURL + "?md=l1"},
+		{Module: "Advanced", Title: "Deep dive", URL: courseURL + "?md=l2"},
+	}
+
+	result := filterLessonLinks(links, courseURL)
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("filterLessonLinks() = %v, want %v", result, expected)
+	}
+}
+
+func TestLessonVideos(t *testing.T) {
+	video := func(id string) VideoRef {
+		return VideoRef{VideoID: id, URL: loomShareURL(id)}
+	}
+	lessons := []Lesson{
+		{Title: "One", Videos: []VideoRef{video("a"), video("b")}},
+		{Title: "Two"},
+		{Title: "Three", Videos: []VideoRef{video("b"), video("c")}},
+	}
+
+	expected := []VideoRef{video("a"), video("b"), video("c")}
+
+	result := lessonVideos(lessons)
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("lessonVideos() = %v, want %v", result, expected)
+	}
+}
+
+func TestLessonAttach(t *testing.T) {
+	lesson := Lesson{
+		Community: "school", Course: "Course", Module: "Basics", ModuleIndex: 2,
+		Title: "Setup", Index: 3, URL: "https://www.skool.com/school/classroom/abc?md=l1",
+	}
+	video := VideoRef{VideoID: "a"}
+	lesson.attach(&video)
+
+	expected := VideoRef{
+		VideoID: "a", Community: "school", Course: "Course", Module: "Basics", ModuleIndex: 2,
+		LessonTitle: "Setup", LessonIndex: 3,
+	}
+	if !reflect.DeepEqual(video, expected) {
+		t.Errorf("attach() = %v, want %v", video, expected)
+	}
+}
+
+func TestIsClassroomIndex(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected bool
+	}{
+		{"https://www.skool.com/school/classroom", true},
+		{"https://www.skool.com/school/classroom/", true},
+		{"https://www.skool.com/school/classroom?p=2", true},
+		{"https://www.skool.com/school/classroom/abc123", false},
+		{"https://www.skool.com/school/classroom/abc123?md=l1", false},
+		{"https://www.skool.com/school", false},
+		{"https://www.skool.com/school/about", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			if result := isClassroomIndex(tt.input); result != tt.expected {
+				t.Errorf("isClassroomIndex(%q) = %v, want %v", tt.input, result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestSamePath(t *testing.T) {
+	course := "https://www.skool.com/school/classroom/abc123"
+	tests := []struct {
+		current  string
+		expected bool
+	}{
+		{"https://www.skool.com/school/classroom/abc123?md=l1", true},
+		{"https://www.skool.com/school/classroom/abc123/", true},
+		// Skool redirects locked courses to the classroom index or the about page
+		{"https://www.skool.com/school/classroom", false},
+		{"https://www.skool.com/school/about", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.current, func(t *testing.T) {
+			if result := samePath(tt.current, course); result != tt.expected {
+				t.Errorf("samePath(%q, %q) = %v, want %v", tt.current, course, result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestFilterCourseCards(t *testing.T) {
+	cards := []courseCard{
+		{Title: " Getting Started ", URL: "https://www.skool.com/school/classroom/abc"},
+		{Title: "Getting Started", URL: "https://www.skool.com/school/classroom/abc"},
+		{Title: "", URL: "https://www.skool.com/school/classroom/def"},
+		{Title: "Broken", URL: ""},
+	}
+
+	expected := []courseCard{
+		{Title: "Getting Started", URL: "https://www.skool.com/school/classroom/abc"},
+		{Title: "/school/classroom/def", URL: "https://www.skool.com/school/classroom/def"},
+	}
+
+	result := filterCourseCards(cards)
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("filterCourseCards() = %v, want %v", result, expected)
+	}
+}
+
+func TestCourseLessons(t *testing.T) {
+	results := []CourseResult{
+		{Title: "A", Status: courseStatusOK, Lessons: []Lesson{{Title: "A1"}, {Title: "A2"}}},
+		{Title: "B", Status: courseStatusLocked},
+		{Title: "C", Status: courseStatusOK, Lessons: []Lesson{{Title: "C1"}}},
+	}
+
+	lessons := courseLessons(results)
+	if len(lessons) != 3 {
+		t.Fatalf("Expected 3 lessons, got %d", len(lessons))
+	}
+	if lessons[2].Title != "C1" {
+		t.Errorf("Expected last lesson 'C1', got '%s'", lessons[2].Title)
+	}
+}
+
+func TestBuildLessons(t *testing.T) {
+	links := []lessonLink{
+		{Title: "Welcome", URL: "u0"},
+		{Module: "Basics", Title: "Setup", URL: "u1"},
+		{Module: "Basics", Title: "First steps", URL: "u2"},
+		{Module: "Advanced", Title: "Deep dive", URL: "u3"},
+		{Module: "Basics", Title: "Recap", URL: "u4"},
+	}
+
+	expected := []Lesson{
+		{Community: "school", Course: "Course", Title: "Welcome", Index: 1, URL: "u0"},
+		{Community: "school", Course: "Course", Module: "Basics", ModuleIndex: 1, Title: "Setup", Index: 1, URL: "u1"},
+		{Community: "school", Course: "Course", Module: "Basics", ModuleIndex: 1, Title: "First steps", Index: 2, URL: "u2"},
+		{Community: "school", Course: "Course", Module: "Advanced", ModuleIndex: 2, Title: "Deep dive", Index: 1, URL: "u3"},
+		{Community: "school", Course: "Course", Module: "Basics", ModuleIndex: 1, Title: "Recap", Index: 3, URL: "u4"},
+	}
+
+	result := buildLessons(links, "school", "Course")
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("buildLessons() = %v, want %v", result, expected)
+	}
+}
+
+func TestCommunitySlug(t *testing.T) {
+	if result := communitySlug("https://www.skool.com/school/classroom/abc?md=1"); result != "school" {
+		t.Errorf("communitySlug() = %q, want %q", result, "school")
+	}
+}
+
+func TestPrintLessonReport(t *testing.T) {
+	var out bytes.Buffer
+	defaultLogger := slog.Default()
+	slog.SetDefault(newLogger(&out, slog.LevelInfo, logFormatText))
+	t.Cleanup(func() {
+		slog.SetDefault(defaultLogger)
+	})
+
+	printLessonReport([]Lesson{
+		{Module: "Basics", Title: "Setup", Videos: []VideoRef{{URL: loomShareURL("abc123")}, {URL: loomShareURL("def456")}}},
+		{Module: "Basics", Title: "No video"},
+	})
+
+	// Every video is reported with its lesson at the default level
+	for _, id := range []string{"abc123", "def456"} {
+		if !strings.Contains(out.String(), loomShareURL(id)) {
+			t.Errorf("Expected %s in the report, got:\n%s", loomShareURL(id), out.String())
+		}
+	}
+	if strings.Count(out.String(), "Basics › Setup") != 2 || strings.Contains(out.String(), "No video") {
+		t.Errorf("Expected one line per video with its lesson, got:\n%s", out.String())
+	}
+}
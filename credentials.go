@@ -0,0 +1,231 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"runtime"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+// stdinPath is the value of -cookies and -from that reads from stdin
+const stdinPath = "-"
+
+// resolveCredentials checks the password sources, which are only read once a
+// login is needed, see requirePassword. Cookies passed on stdin with -cookies=- or read
+// from a browser profile are written to a private temporary file, since
+// yt-dlp needs a file path. The returned cleanup function removes that file.
+func resolveCredentials(config *Config, stdin *os.File) (cleanup func(), err error) {
+	cleanup = func() {}
+	if err := checkCredentialSources(*config); err != nil {
+		return cleanup, err
+	}
+
+	if config.CookiesFrom != "" {
+		path, err := writeBrowserCookies(config.CookiesFrom)
+		if err != nil {
+			return cleanup, fmt.Errorf("error reading cookies from browser: %v", err)
+		}
+		config.CookiesFile = path
+		cleanup = func() {
+			_ = os.Remove(path)
+		}
+	} else if config.CookiesFile == stdinPath {
+		path, err := writeStdinCookies(stdin)
+		if err != nil {
+			return cleanup, fmt.Errorf("error reading cookies from stdin: %v", err)
+		}
+		config.CookiesFile = path
+		cleanup = func() {
+			_ = os.Remove(path)
+		}
+	} else if config.CookiesFile != "" {
+		if err := checkSecretFilePermissions(config.CookiesFile); err != nil {
+			slog.Warn("⚠️ Cookies file is readable by other users", "path", config.CookiesFile, "error", err)
+		}
+	}
+	return cleanup, nil
+}
+
+// checkCredentialSources rejects more than one password source and more
+// than one option reading from stdin
+func checkCredentialSources(config Config) error {
+	passwordSources := 0
+	for _, set := range []bool{config.Password != "", config.PasswordFile != "", config.PasswordStdin} {
+		if set {
+			passwordSources++
+		}
+	}
+	if passwordSources > 1 {
+		return fmt.Errorf("-password, -password-file and -password-stdin cannot be used together")
+	}
+
+	if config.CookiesFile != "" && config.CookiesFrom != "" {
+		return fmt.Errorf("-cookies and -cookies-from-browser cannot be used together")
+	}
+
+	stdinReaders := 0
+	for _, set := range []bool{config.PasswordStdin, config.CookiesFile == stdinPath, config.From == stdinPath} {
+		if set {
+			stdinReaders++
+		}
+	}
+	if stdinReaders > 1 {
+		return fmt.Errorf("only one of -password-stdin, -cookies - and -from - can read from stdin")
+	}
+	return nil
+}
+
+// readSecretFile reads a secret from the first line of a file that only its
+// owner can read
+func readSecretFile(path string) (string, error) {
+	if err := checkSecretFilePermissions(path); err != nil {
+		return "", err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+	return readSecretLine(f)
+}
+
+// checkSecretFilePermissions fails when a file can be read or written by
+// users other than its owner. Windows has no such permission bits, so the
+// check is skipped there.
+func checkSecretFilePermissions(path string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if runtime.GOOS == "windows" {
+		return nil
+	}
+	if perm := info.Mode().Perm(); perm&0077 != 0 {
+		return fmt.Errorf("%s has permissions %04o, restrict them with chmod 600", path, perm)
+	}
+	return nil
+}
+
+// readSecretLine reads the first line of r without its line ending
+func readSecretLine(r io.Reader) (string, error) {
+	line, err := bufio.NewReader(r).ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return "", err
+	}
+	secret := strings.TrimRight(line, "\r\n")
+	if secret == "" {
+		return "", fmt.Errorf("no secret given")
+	}
+	return secret, nil
+}
+
+// requirePassword fills in config.Password for a login from -password-file,
+// -password-stdin or a prompt on the terminal when no source is given. Runs
+// that never log in, such as -from, -check-cookies or those reusing a saved
+// session, neither read nor ask for the password.
+func requirePassword(config *Config, stdin *os.File) error {
+	var err error
+	switch {
+	case config.Password != "":
+		return nil
+	case config.PasswordFile != "":
+		if config.Password, err = readSecretFile(config.PasswordFile); err != nil {
+			return fmt.Errorf("error reading password file: %v", err)
+		}
+		return nil
+	case config.PasswordStdin:
+		if config.Password, err = readSecretLine(stdin); err != nil {
+			return fmt.Errorf("error reading password from stdin: %v", err)
+		}
+		return nil
+	}
+
+	if !term.IsTerminal(int(stdin.Fd())) {
+		return fmt.Errorf("no password given for %s, use -password-file, -password-stdin or -password", config.Email)
+	}
+	password, err := promptPassword(config.Email, stdin)
+	if err != nil {
+		return fmt.Errorf("error reading password: %v", err)
+	}
+	config.Password = password
+	return nil
+}
+
+// promptPassword asks for the password on the terminal without echoing it
+func promptPassword(email string, stdin *os.File) (string, error) {
+	_, _ = fmt.Fprintf(os.Stderr, "🔑 Password for %s: ", email)
+	password, err := term.ReadPassword(int(stdin.Fd()))
+	_, _ = fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", err
+	}
+	return string(password), nil
+}
+
+// writeStdinCookies copies cookies from stdin to a temporary file that only
+// the current user can read and returns its path. The extension tells the
+// cookie parsers whether the content is JSON or in Netscape format.
+func writeStdinCookies(stdin io.Reader) (string, error) {
+	content, err := io.ReadAll(stdin)
+	if err != nil {
+		return "", err
+	}
+	if len(strings.TrimSpace(string(content))) == 0 {
+		return "", fmt.Errorf("no cookies given")
+	}
+
+	pattern := "skool-loom-dl-cookies-*.txt"
+	if trimmed := strings.TrimSpace(string(content)); strings.HasPrefix(trimmed, "[") || strings.HasPrefix(trimmed, "{") {
+		pattern = "skool-loom-dl-cookies-*.json"
+	}
+
+	return writePrivateTempFile("", pattern, content)
+}
+
+// writeBrowserCookies copies the Skool and Loom cookies of a browser profile
+// to a temporary JSON cookie file and returns its path
+func writeBrowserCookies(spec string) (string, error) {
+	cookies, err := loadBrowserCookies(spec)
+	if err != nil {
+		return "", err
+	}
+	if len(cookies) == 0 {
+		return "", fmt.Errorf("no Skool or Loom cookies found in %s, log in to Skool in that browser first", spec)
+	}
+	slog.Info("🍪 Read cookies from browser", "browser", spec, "cookies", len(cookies))
+
+	content, err := json.Marshal(cookieParamsToJSON(cookies))
+	if err != nil {
+		return "", err
+	}
+	return writePrivateTempFile("", "skool-loom-dl-cookies-*.json", content)
+}
+
+// writePrivateTempFile writes content to a new temporary file in dir that
+// only the current user can read and returns its path
+func writePrivateTempFile(dir, pattern string, content []byte) (string, error) {
+	// CreateTemp creates the file with mode 0600
+	f, err := os.CreateTemp(dir, pattern)
+	if err != nil {
+		return "", err
+	}
+	if _, err := f.Write(content); err != nil {
+		_ = f.Close()
+		_ = os.Remove(f.Name())
+		return "", err
+	}
+	if err := f.Close(); err != nil {
+		_ = os.Remove(f.Name())
+		return "", err
+	}
+	return f.Name(), nil
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// writeTestSecret writes content to a file with the given permissions
+func writeTestSecret(t *testing.T, name, content string, perm os.FileMode) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), perm); err != nil {
+		t.Fatalf("Failed to write %s: %v", name, err)
+	}
+	if err := os.Chmod(path, perm); err != nil {
+		t.Fatalf("Failed to chmod %s: %v", name, err)
+	}
+	return path
+}
+
+// testStdin returns a file to use as stdin that contains content
+func testStdin(t *testing.T, content string) *os.File {
+	t.Helper()
+	f, err := os.Open(writeTestSecret(t, "stdin", content, 0600))
+	if err != nil {
+		t.Fatalf("Failed to open stdin file: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = f.Close()
+	})
+	return f
+}
+
+// TestResolveCredentials checks the password sources as a login reads them
+func TestResolveCredentials(t *testing.T) {
+	tests := []struct {
+		name         string
+		config       Config
+		stdin        string
+		wantPassword string
+		wantErr      string
+	}{
+		{
+			name:         "Password flag is kept",
+			config:       Config{Email: "a@b.c", Password: "pw"},
+			wantPassword: "pw",
+		},
+		{
+			name:         "Password from stdin",
+			config:       Config{Email: "a@b.c", PasswordStdin: true},
+			stdin:        "secret\nignored\n",
+			wantPassword: "secret",
+		},
+		{
+			name:         "Password from stdin with CRLF",
+			config:       Config{Email: "a@b.c", PasswordStdin: true},
+			stdin:        "secret\r\n",
+			wantPassword: "secret",
+		},
+		{
+			name:    "Empty stdin",
+			config:  Config{Email: "a@b.c", PasswordStdin: true},
+			wantErr: "no secret given",
+		},
+		{
+			name:    "Two password sources",
+			config:  Config{Email: "a@b.c", Password: "pw", PasswordStdin: true},
+			wantErr: "cannot be used together",
+		},
+		{
+			name:    "Password and cookies both on stdin",
+			config:  Config{Email: "a@b.c", PasswordStdin: true, CookiesFile: "-"},
+			wantErr: "can read from stdin",
+		},
+		{
+			name:    "Password and video list both on stdin",
+			config:  Config{PasswordStdin: true, From: "-"},
+			wantErr: "can read from stdin",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := tt.config
+			stdin := testStdin(t, tt.stdin)
+			cleanup, err := resolveCredentials(&config, stdin)
+			defer cleanup()
+			if err == nil {
+				err = requirePassword(&config, stdin)
+			}
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("resolveCredentials() error = %v, want containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("resolveCredentials() error = %v", err)
+			}
+			if config.Password != tt.wantPassword {
+				t.Errorf("Password = %q, want %q", config.Password, tt.wantPassword)
+			}
+		})
+	}
+}
+
+func TestResolveCredentials_PasswordFile(t *testing.T) {
+	config := Config{Email: "a@b.c", PasswordFile: writeTestSecret(t, "password", "secret\n", 0600)}
+	if err := requirePassword(&config, testStdin(t, "")); err != nil {
+		t.Fatalf("requirePassword() error = %v", err)
+	}
+	if config.Password != "secret" {
+		t.Errorf("Password = %q, want %q", config.Password, "secret")
+	}
+}
+
+func TestResolveCredentials_PasswordFileReadableByOthers(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("file permissions are not checked on Windows")
+	}
+	config := Config{Email: "a@b.c", PasswordFile: writeTestSecret(t, "password", "secret\n", 0644)}
+	err := requirePassword(&config, testStdin(t, ""))
+	if err == nil || !strings.Contains(err.Error(), "chmod 600") {
+		t.Fatalf("requirePassword() error = %v, want permission error", err)
+	}
+	if config.Password != "" {
+		t.Errorf("Password = %q, want it unset", config.Password)
+	}
+}
+
+func TestResolveCredentials_CookiesFromStdin(t *testing.T) {
+	cookies := `[{"name": "auth_token", "value": "abc", "domain": ".skool.com", "path": "/"}]`
+	config := Config{CookiesFile: "-"}
+	cleanup, err := resolveCredentials(&config, testStdin(t, cookies))
+	if err != nil {
+		t.Fatalf("resolveCredentials() error = %v", err)
+	}
+	path := config.CookiesFile
+	if path == "-" || !strings.HasSuffix(path, ".json") {
+		t.Fatalf("CookiesFile = %q, want a temporary JSON file", path)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("Failed to stat cookies file: %v", err)
+	}
+	if runtime.GOOS != "windows" && info.Mode().Perm() != 0600 {
+		t.Errorf("cookies file permissions = %04o, want 0600", info.Mode().Perm())
+	}
+	parsed, err := parseCookiesFile(path)
+	if err != nil || len(parsed) != 1 || parsed[0].Name != "auth_token" {
+		t.Errorf("parseCookiesFile() = %v, %v, want the auth_token cookie", parsed, err)
+	}
+
+	cleanup()
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("cookies file still exists after cleanup: %v", err)
+	}
+}
+
+func TestResolveCredentials_PasswordNotReadWithoutLogin(t *testing.T) {
+	// A saved session may make the login unnecessary, so stdin is left alone
+	config := Config{Email: "a@b.c", PasswordStdin: true}
+	cleanup, err := resolveCredentials(&config, testStdin(t, "secret\n"))
+	defer cleanup()
+	if err != nil || config.Password != "" {
+		t.Errorf("resolveCredentials() error = %v, password = %q, want the password left unread", err, config.Password)
+	}
+}
+
+func TestRequirePassword(t *testing.T) {
+	config := Config{Email: "a@b.c", Password: "pw"}
+	if err := requirePassword(&config, testStdin(t, "")); err != nil || config.Password != "pw" {
+		t.Errorf("requirePassword() error = %v, password = %q, want the given password kept", err, config.Password)
+	}
+
+	// Without a terminal there is nobody to ask
+	config = Config{Email: "a@b.c"}
+	if err := requirePassword(&config, testStdin(t, "secret\n")); err == nil || !strings.Contains(err.Error(), "-password-file") {
+		t.Errorf("requirePassword() error = %v, want a missing password error", err)
+	}
+}
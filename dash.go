@@ -0,0 +1,343 @@
+package main
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"net/url"
+	"os"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// dashManifest is the subset of an MPEG-DASH MPD document needed to list the
+// segments of each representation
+type dashManifest struct {
+	MediaPresentationDuration string       `xml:"mediaPresentationDuration,attr"`
+	BaseURL                   string       `xml:"BaseURL"`
+	Periods                   []dashPeriod `xml:"Period"`
+}
+
+type dashPeriod struct {
+	BaseURL        string              `xml:"BaseURL"`
+	AdaptationSets []dashAdaptationSet `xml:"AdaptationSet"`
+}
+
+type dashAdaptationSet struct {
+	MimeType        string               `xml:"mimeType,attr"`
+	ContentType     string               `xml:"contentType,attr"`
+	BaseURL         string               `xml:"BaseURL"`
+	SegmentTemplate *dashSegmentTemplate `xml:"SegmentTemplate"`
+	Representations []dashRepresentation `xml:"Representation"`
+}
+
+type dashRepresentation struct {
+	ID              string               `xml:"id,attr"`
+	Bandwidth       int                  `xml:"bandwidth,attr"`
+	MimeType        string               `xml:"mimeType,attr"`
+	BaseURL         string               `xml:"BaseURL"`
+	SegmentTemplate *dashSegmentTemplate `xml:"SegmentTemplate"`
+	SegmentList     *dashSegmentList     `xml:"SegmentList"`
+}
+
+type dashSegmentTemplate struct {
+	Initialization string `xml:"initialization,attr"`
+	Media          string `xml:"media,attr"`
+	StartNumber    *int   `xml:"startNumber,attr"`
+	Timescale      int    `xml:"timescale,attr"`
+	Duration       int    `xml:"duration,attr"`
+	Timeline       []struct {
+		T *int64 `xml:"t,attr"`
+		D int64  `xml:"d,attr"`
+		R int    `xml:"r,attr"`
+	} `xml:"SegmentTimeline>S"`
+}
+
+type dashSegmentList struct {
+	Initialization struct {
+		SourceURL string `xml:"sourceURL,attr"`
+	} `xml:"Initialization"`
+	SegmentURLs []struct {
+		Media string `xml:"media,attr"`
+	} `xml:"SegmentURL"`
+}
+
+// dashTrack is a representation resolved to the URLs of its segments, with
+// the initialization segment first
+type dashTrack struct {
+	Bandwidth int
+	Segments  []string
+}
+
+// dashStream holds the best video and audio track of a manifest. Audio is nil
+// when the video track carries its own audio.
+type dashStream struct {
+	Video *dashTrack
+	Audio *dashTrack
+}
+
+var (
+	dashTemplatePattern = regexp.MustCompile(`\$(RepresentationID|Number|Time|Bandwidth)(%0(\d+)d)?\$`)
+	isoDurationPattern  = regexp.MustCompile(`^P(?:([\d.]+)D)?(?:T(?:([\d.]+)H)?(?:([\d.]+)M)?(?:([\d.]+)S)?)?$`)
+)
+
+// parseDASHManifest parses an MPD document and picks the highest bandwidth
+// video and audio representations. Segment URIs are resolved like HLS URIs,
+// inheriting the signed query of base.
+func parseDASHManifest(content []byte, base *url.URL) (*dashStream, error) {
+	var manifest dashManifest
+	if err := xml.Unmarshal(content, &manifest); err != nil {
+		return nil, fmt.Errorf("not a DASH manifest: %v", err)
+	}
+	if len(manifest.Periods) == 0 {
+		return nil, fmt.Errorf("DASH manifest has no periods")
+	}
+	if len(manifest.Periods) > 1 {
+		return nil, fmt.Errorf("%w: multi-period DASH", errUnsupportedStream)
+	}
+	duration, err := parseISODuration(manifest.MediaPresentationDuration)
+	if err != nil {
+		return nil, err
+	}
+
+	period := manifest.Periods[0]
+	periodBase := resolveBaseURL(resolveBaseURL(base, manifest.BaseURL), period.BaseURL)
+
+	stream := &dashStream{}
+	for _, set := range period.AdaptationSets {
+		setBase := resolveBaseURL(periodBase, set.BaseURL)
+		for _, rep := range set.Representations {
+			kind := dashContentType(set, rep)
+			if kind != "video" && kind != "audio" {
+				continue
+			}
+
+			track, err := dashRepresentationTrack(set, rep, resolveBaseURL(setBase, rep.BaseURL), duration)
+			if err != nil {
+				return nil, fmt.Errorf("representation %s: %w", rep.ID, err)
+			}
+
+			best := &stream.Video
+			if kind == "audio" {
+				best = &stream.Audio
+			}
+			if *best == nil || track.Bandwidth > (*best).Bandwidth {
+				*best = track
+			}
+		}
+	}
+
+	if stream.Video == nil {
+		return nil, fmt.Errorf("DASH manifest has no video")
+	}
+	return stream, nil
+}
+
+func dashContentType(set dashAdaptationSet, rep dashRepresentation) string {
+	for _, t := range []string{set.ContentType, rep.MimeType, set.MimeType} {
+		if t != "" {
+			kind, _, _ := strings.Cut(t, "/")
+			return kind
+		}
+	}
+	return ""
+}
+
+// dashRepresentationTrack lists the segments of a representation from its
+// segment template, segment list or single-file base URL
+func dashRepresentationTrack(set dashAdaptationSet, rep dashRepresentation, base *url.URL, duration float64) (*dashTrack, error) {
+	track := &dashTrack{Bandwidth: rep.Bandwidth}
+
+	template := rep.SegmentTemplate
+	if template == nil {
+		template = set.SegmentTemplate
+	}
+
+	switch {
+	case rep.SegmentList != nil:
+		if init := rep.SegmentList.Initialization.SourceURL; init != "" {
+			track.Segments = append(track.Segments, resolveMediaURL(base, init))
+		}
+		for _, s := range rep.SegmentList.SegmentURLs {
+			track.Segments = append(track.Segments, resolveMediaURL(base, s.Media))
+		}
+	case template != nil:
+		if template.Initialization != "" {
+			track.Segments = append(track.Segments, resolveMediaURL(base, expandDASHTemplate(template.Initialization, rep, 0, 0)))
+		}
+		for _, s := range dashTemplateSegments(template, duration) {
+			track.Segments = append(track.Segments, resolveMediaURL(base, expandDASHTemplate(template.Media, rep, s.number, s.time)))
+		}
+	case rep.BaseURL != "":
+		track.Segments = []string{base.String()}
+	}
+
+	if len(track.Segments) == 0 {
+		return nil, fmt.Errorf("no segments")
+	}
+	return track, nil
+}
+
+type dashSegment struct {
+	number int
+	time   int64
+}
+
+// dashTemplateSegments lists the number and start time of each segment of a
+// template, from its timeline or from the fixed segment duration
+func dashTemplateSegments(template *dashSegmentTemplate, duration float64) []dashSegment {
+	number := 1
+	if template.StartNumber != nil {
+		number = *template.StartNumber
+	}
+
+	var segments []dashSegment
+	if len(template.Timeline) > 0 {
+		var t int64
+		for _, s := range template.Timeline {
+			if s.T != nil {
+				t = *s.T
+			}
+			for i := 0; i <= s.R; i++ {
+				segments = append(segments, dashSegment{number: number, time: t})
+				number++
+				t += s.D
+			}
+		}
+		return segments
+	}
+
+	if template.Duration <= 0 {
+		return nil
+	}
+	timescale := template.Timescale
+	if timescale <= 0 {
+		timescale = 1
+	}
+	count := int(math.Ceil(duration * float64(timescale) / float64(template.Duration)))
+	for i := 0; i < count; i++ {
+		segments = append(segments, dashSegment{number: number + i, time: int64(i) * int64(template.Duration)})
+	}
+	return segments
+}
+
+// expandDASHTemplate substitutes the $Identifier$ placeholders of a segment
+// template, including width formats such as $Number%05d$
+func expandDASHTemplate(template string, rep dashRepresentation, number int, time int64) string {
+	expanded := dashTemplatePattern.ReplaceAllStringFunc(template, func(match string) string {
+		groups := dashTemplatePattern.FindStringSubmatch(match)
+		var value string
+		switch groups[1] {
+		case "RepresentationID":
+			return rep.ID
+		case "Number":
+			value = strconv.Itoa(number)
+		case "Time":
+			value = strconv.FormatInt(time, 10)
+		case "Bandwidth":
+			value = strconv.Itoa(rep.Bandwidth)
+		}
+		if width, _ := strconv.Atoi(groups[3]); len(value) < width {
+			value = strings.Repeat("0", width-len(value)) + value
+		}
+		return value
+	})
+	return strings.ReplaceAll(expanded, "$$", "$")
+}
+
+func resolveBaseURL(base *url.URL, ref string) *url.URL {
+	ref = strings.TrimSpace(ref)
+	if ref == "" {
+		return base
+	}
+	u, err := url.Parse(resolveMediaURL(base, ref))
+	if err != nil {
+		return base
+	}
+	return u
+}
+
+// parseISODuration parses durations such as PT1H2M3.5S into seconds
+func parseISODuration(s string) (float64, error) {
+	if s == "" {
+		return 0, nil
+	}
+	match := isoDurationPattern.FindStringSubmatch(s)
+	if match == nil {
+		return 0, fmt.Errorf("invalid duration %q", s)
+	}
+
+	var seconds float64
+	for i, unit := range []float64{86400, 3600, 60, 1} {
+		if match[i+1] == "" {
+			continue
+		}
+		v, err := strconv.ParseFloat(match[i+1], 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration %q", s)
+		}
+		seconds += v * unit
+	}
+	return seconds, nil
+}
+
+// downloadDASH downloads the best video and audio tracks of the DASH stream at
+// manifestURL with resume support and returns the path of the saved file.
+// Separate audio and video tracks are muxed into one MP4 file, which requires
+// ffmpeg.
+func downloadDASH(ctx context.Context, client mediaFetcher, manifestURL string, outputPath func(ext string) string, out io.Writer) (string, error) {
+	base, err := url.Parse(manifestURL)
+	if err != nil {
+		return "", err
+	}
+	resp, err := client.get(ctx, manifestURL)
+	if err != nil {
+		return "", fmt.Errorf("error reading DASH manifest: %w", err)
+	}
+	content, err := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if err != nil {
+		return "", fmt.Errorf("error reading DASH manifest: %w", err)
+	}
+
+	stream, err := parseDASHManifest(content, base)
+	if err != nil {
+		return "", err
+	}
+
+	target := outputPath("mp4")
+	if stream.Audio == nil {
+		if err := downloadSegments(ctx, client, stream.Video.Segments, target, out); err != nil {
+			return "", err
+		}
+		return target, nil
+	}
+
+	ffmpeg, err := exec.LookPath("ffmpeg")
+	if err != nil {
+		return "", fmt.Errorf("%w: DASH with separate audio needs ffmpeg", errUnsupportedStream)
+	}
+
+	stem := strings.TrimSuffix(target, ".mp4")
+	videoPath, audioPath := stem+".video.mp4", stem+".audio.m4a"
+	_, _ = fmt.Fprintln(out, "[native] Downloading video track")
+	if err := downloadSegments(ctx, client, stream.Video.Segments, videoPath, out); err != nil {
+		return "", fmt.Errorf("video track: %w", err)
+	}
+	_, _ = fmt.Fprintln(out, "[native] Downloading audio track")
+	if err := downloadSegments(ctx, client, stream.Audio.Segments, audioPath, out); err != nil {
+		return "", fmt.Errorf("audio track: %w", err)
+	}
+
+	_, _ = fmt.Fprintf(out, "[native] Muxing into %s\n", target)
+	if err := muxToMP4(ctx, ffmpeg, []string{videoPath, audioPath}, target); err != nil {
+		return "", err
+	}
+	_ = os.Remove(videoPath)
+	_ = os.Remove(audioPath)
+	return target, nil
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"net/url"
+	"reflect"
+	"testing"
+)
+
+func TestParseDASHManifest_Timeline(t *testing.T) {
+	base, _ := url.Parse("https://cdn.example.com/video/manifest.mpd?sig=abc")
+	content := `<?xml version="1.0"?>
+<MPD xmlns="urn:mpeg:dash:schema:mpd:2011" mediaPresentationDuration="PT6S">
+  <Period>
+    <AdaptationSet contentType="video">
+      <SegmentTemplate initialization="$RepresentationID$/init.mp4" media="$RepresentationID$/$Time$.m4s">
+        <SegmentTimeline>
+          <S t="0" d="2000" r="1"/>
+          <S d="2000"/>
+        </SegmentTimeline>
+      </SegmentTemplate>
+      <Representation id="low" bandwidth="500000"/>
+      <Representation id="high" bandwidth="2000000"/>
+    </AdaptationSet>
+    <AdaptationSet mimeType="audio/mp4">
+      <BaseURL>audio/</BaseURL>
+      <Representation id="aac" bandwidth="128000">
+        <SegmentList>
+          <Initialization sourceURL="init.m4a"/>
+          <SegmentURL media="seg1.m4a"/>
+          <SegmentURL media="https://other.example.com/seg2.m4a?token=1"/>
+        </SegmentList>
+      </Representation>
+    </AdaptationSet>
+  </Period>
+</MPD>`
+
+	stream, err := parseDASHManifest([]byte(content), base)
+	if err != nil {
+		t.Fatalf("parseDASHManifest() error = %v", err)
+	}
+
+	expectedVideo := []string{
+		"https://cdn.example.com/video/high/init.mp4?sig=abc",
+		"https://cdn.example.com/video/high/0.m4s?sig=abc",
+		"https://cdn.example.com/video/high/2000.m4s?sig=abc",
+		"https://cdn.example.com/video/high/4000.m4s?sig=abc",
+	}
+	if !reflect.DeepEqual(stream.Video.Segments, expectedVideo) {
+		t.Errorf("Video segments = %v, want %v", stream.Video.Segments, expectedVideo)
+	}
+
+	if stream.Audio == nil {
+		t.Fatal("Expected an audio track")
+	}
+	expectedAudio := []string{
+		"https://cdn.example.com/video/audio/init.m4a?sig=abc",
+		"https://cdn.example.com/video/audio/seg1.m4a?sig=abc",
+		"https://other.example.com/seg2.m4a?token=1",
+	}
+	if !reflect.DeepEqual(stream.Audio.Segments, expectedAudio) {
+		t.Errorf("Audio segments = %v, want %v", stream.Audio.Segments, expectedAudio)
+	}
+}
+
+func TestParseDASHManifest_Invalid(t *testing.T) {
+	base, _ := url.Parse("https://cdn.example.com/video/manifest.mpd")
+	tests := map[string]string{
+		"not xml":  "#EXTM3U",
+		"no video": `<MPD><Period><AdaptationSet contentType="audio"><Representation id="a"><BaseURL>a.mp4</BaseURL></Representation></AdaptationSet></Period></MPD>`,
+	}
+	for name, content := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := parseDASHManifest([]byte(content), base); err == nil {
+				t.Error("Expected error, got nil")
+			}
+		})
+	}
+}
+
+func TestDashTemplateSegments_Duration(t *testing.T) {
+	start := 5
+	template := &dashSegmentTemplate{StartNumber: &start, Timescale: 1000, Duration: 4000}
+
+	segments := dashTemplateSegments(template, 10)
+	expected := []dashSegment{{number: 5, time: 0}, {number: 6, time: 4000}, {number: 7, time: 8000}}
+	if !reflect.DeepEqual(segments, expected) {
+		t.Errorf("dashTemplateSegments() = %v, want %v", segments, expected)
+	}
+}
+
+func TestExpandDASHTemplate(t *testing.T) {
+	rep := dashRepresentation{ID: "video-1", Bandwidth: 800000}
+
+	tests := []struct {
+		template string
+		expected string
+	}{
+		{"$RepresentationID$/$Number$.m4s", "video-1/7.m4s"},
+		{"seg-$Number%05d$.m4s", "seg-00007.m4s"},
+		{"t$Time$-b$Bandwidth$.m4s", "t12000-b800000.m4s"},
+		{"price$$.m4s", "price$.m4s"},
+	}
+	for _, tt := range tests {
+		if got := expandDASHTemplate(tt.template, rep, 7, 12000); got != tt.expected {
+			t.Errorf("expandDASHTemplate(%q) = %q, want %q", tt.template, got, tt.expected)
+		}
+	}
+}
+
+func TestParseISODuration(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected float64
+		wantErr  bool
+	}{
+		{input: "PT1H2M3.5S", expected: 3723.5},
+		{input: "PT45S", expected: 45},
+		{input: "P1DT1M", expected: 86460},
+		{input: "", expected: 0},
+		{input: "1 hour", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := parseISODuration(tt.input)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseISODuration(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			continue
+		}
+		if got != tt.expected {
+			t.Errorf("parseISODuration(%q) = %v, want %v", tt.input, got, tt.expected)
+		}
+	}
+}
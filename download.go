@@ -0,0 +1,212 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+)
+
+// Download outcomes reported per video
+const (
+	statusDownloaded    = "downloaded"
+	statusWouldDownload = "would_download"
+	statusSkipped       = "skipped"
+	statusFailed        = "failed"
+)
+
+// downloadResult is the outcome of downloading a single video. Path and
+// Bytes are only known for finished downloads.
+type downloadResult struct {
+	Video    VideoRef
+	Status   string
+	Err      error
+	Path     string
+	Bytes    int64
+	Duration time.Duration
+}
+
+// downloadJob is a video queued for download with its 1-based queue position
+type downloadJob struct {
+	Index int
+	Video VideoRef
+}
+
+// downloadAll downloads up to config.Concurrency videos at once with the given
+// downloader and returns one result per video in queue order. Videos in the
+// archive are skipped unless config.Force is set. Once ctx is cancelled no new
+// downloads are started and running ones are stopped.
+func downloadAll(ctx context.Context, videos []VideoRef, downloader Downloader, config Config, archive *downloadArchive) []downloadResult {
+	workers := config.Concurrency
+	if workers < 1 {
+		workers = 1
+	}
+	if workers > len(videos) {
+		workers = len(videos)
+	}
+
+	results := make([]downloadResult, len(videos))
+	jobs := make(chan downloadJob)
+
+	var wg sync.WaitGroup
+	var outputMu sync.Mutex
+	for w := 1; w <= workers; w++ {
+		logger := slog.Default()
+		var stdout, stderr io.Writer = os.Stdout, os.Stderr
+		if workers > 1 {
+			logger = logger.With("worker", w)
+			prefix := fmt.Sprintf("[worker %d] ", w)
+			stdout = newPrefixWriter(os.Stdout, prefix, &outputMu)
+			stderr = newPrefixWriter(os.Stderr, prefix, &outputMu)
+		}
+
+		// Downloader output is progress meant for a terminal: turn it into
+		// debug records for JSON logs and drop it when running quietly
+		switch level, _ := parseLogLevel(config.LogLevel); {
+		case config.LogFormat == logFormatJSON:
+			stdout = newLogWriter(logger, slog.LevelDebug)
+			stderr = newLogWriter(logger, slog.LevelDebug)
+		case level > slog.LevelInfo:
+			stdout, stderr = io.Discard, io.Discard
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range jobs {
+				results[job.Index-1] = downloadVideo(ctx, job, len(videos), downloader, config, archive, logger, stdout, stderr)
+				flushWriters(stdout, stderr)
+			}
+		}()
+	}
+
+	for i, video := range videos {
+		jobs <- downloadJob{Index: i + 1, Video: video}
+	}
+	close(jobs)
+	wg.Wait()
+
+	return results
+}
+
+func downloadVideo(ctx context.Context, job downloadJob, total int, downloader Downloader, config Config, archive *downloadArchive, logger *slog.Logger, stdout, stderr io.Writer) downloadResult {
+	video := job.Video
+	progress := fmt.Sprintf("%d/%d", job.Index, total)
+	if !config.Force && archive.Has(video) {
+		logger.Info("⏭️ Already downloaded, skipping", "video", progress, "url", video.URL)
+		return downloadResult{Video: video, Status: statusSkipped}
+	}
+	if ctx.Err() != nil {
+		return downloadResult{Video: video, Status: statusFailed, Err: ctx.Err()}
+	}
+
+	logger.Info("📥 Downloading", "video", progress, "url", video.URL)
+	start := time.Now()
+	var path string
+	err := retry(ctx, config.retryPolicy(), "Download", logger, func() error {
+		var err error
+		path, err = downloader.Download(ctx, video, stdout, stderr)
+		return err
+	})
+	result := downloadResult{Video: video, Status: statusDownloaded, Path: path, Duration: time.Since(start)}
+	if err != nil {
+		logger.Error("❌ Download failed", "url", video.URL, "error", err)
+		result.Status, result.Err = statusFailed, err
+		return result
+	}
+	if info, err := os.Stat(path); path != "" && err == nil {
+		result.Bytes = info.Size()
+	}
+
+	// A dry run fetches nothing, so there is nothing to remember
+	if config.Downloader == downloaderDryRun {
+		result.Status = statusWouldDownload
+		return result
+	}
+	logger.Debug("✅ Downloaded", "url", video.URL, "path", path, "bytes", result.Bytes, "duration", result.Duration.Round(time.Millisecond))
+	if err := archive.Add(video); err != nil {
+		logger.Warn("⚠️ Couldn't update download archive", "error", err)
+	}
+	return result
+}
+
+func printDownloadSummary(results []downloadResult) {
+	counts := make(map[string]int)
+	for _, result := range results {
+		counts[result.Status]++
+	}
+
+	args := []any{"downloaded", counts[statusDownloaded], "skipped", counts[statusSkipped], "failed", counts[statusFailed]}
+	if counts[statusWouldDownload] > 0 {
+		args = append(args, "would_download", counts[statusWouldDownload])
+	}
+	slog.Info("📊 Download summary", args...)
+	for _, result := range results {
+		if result.Status == statusFailed {
+			slog.Error("❌ Failed", "url", result.Video.URL, "error", result.Err)
+		}
+	}
+}
+
+// prefixWriter prefixes every line written to it, so output of concurrent
+// yt-dlp processes stays readable. Carriage returns used for progress updates
+// are treated as line ends. Complete lines are written under a shared lock.
+type prefixWriter struct {
+	out    io.Writer
+	prefix string
+	mu     *sync.Mutex
+	buf    []byte
+}
+
+func newPrefixWriter(out io.Writer, prefix string, mu *sync.Mutex) *prefixWriter {
+	return &prefixWriter{out: out, prefix: prefix, mu: mu}
+}
+
+func (w *prefixWriter) Write(p []byte) (int, error) {
+	w.buf = append(w.buf, p...)
+	for {
+		i := bytes.IndexAny(w.buf, "\r\n")
+		if i < 0 {
+			break
+		}
+		line := w.buf[:i]
+		w.buf = w.buf[i+1:]
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+		if err := w.writeLine(line); err != nil {
+			return len(p), err
+		}
+	}
+	return len(p), nil
+}
+
+// Flush writes any buffered partial line
+func (w *prefixWriter) Flush() error {
+	if len(w.buf) == 0 {
+		return nil
+	}
+	line := w.buf
+	w.buf = nil
+	return w.writeLine(line)
+}
+
+func (w *prefixWriter) writeLine(line []byte) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	_, err := fmt.Fprintf(w.out, "%s%s\n", w.prefix, line)
+	return err
+}
+
+// flushWriters writes out partial lines buffered by prefix and log writers
+func flushWriters(writers ...io.Writer) {
+	for _, w := range writers {
+		if f, ok := w.(interface{ Flush() error }); ok {
+			_ = f.Flush()
+		}
+	}
+}
@@ -0,0 +1,294 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestPrefixWriter(t *testing.T) {
+	var out bytes.Buffer
+	var mu sync.Mutex
+	w := newPrefixWriter(&out, "[worker 1] ", &mu)
+
+	writes := []string{
+		"[download] Destination: video.mp4\n",
+		"[download]  10.0% of 5.00MiB\r[download]  50.0%",
+		" of 5.00MiB\r\n",
+		"\n",
+		"partial line",
+	}
+	for _, s := range writes {
+		if _, err := w.Write([]byte(s)); err != nil {
+			t.Fatalf("Write() error = %v", err)
+		}
+	}
+
+	expected := "[worker 1] [download] Destination: video.mp4\n" +
+		"[worker 1] [download]  10.0% of 5.00MiB\n" +
+		"[worker 1] [download]  50.0% of 5.00MiB\n"
+	if out.String() != expected {
+		t.Errorf("Unexpected output before flush:\n%q\nwant\n%q", out.String(), expected)
+	}
+
+	if err := w.Flush(); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+	expected += "[worker 1] partial line\n"
+	if out.String() != expected {
+		t.Errorf("Unexpected output after flush:\n%q\nwant\n%q", out.String(), expected)
+	}
+}
+
+func TestDownloadAll_SkipsArchivedVideos(t *testing.T) {
+	archive, err := openArchive(filepath.Join(t.TempDir(), "archive"))
+	if err != nil {
+		t.Fatalf("openArchive() error = %v", err)
+	}
+
+	var videos []VideoRef
+	for _, id := range []string{"a", "b", "c", "d"} {
+		if err := archive.Add(loomRef(id)); err != nil {
+			t.Fatalf("Add() error = %v", err)
+		}
+		videos = append(videos, VideoRef{Provider: providerLoom, VideoID: id, URL: loomShareURL(id)})
+	}
+
+	config := Config{OutputDir: t.TempDir(), Concurrency: 3}
+	downloader := &fakeDownloader{}
+	results := downloadAll(context.Background(), videos, downloader, config, archive)
+
+	if len(results) != len(videos) {
+		t.Fatalf("Expected %d results, got %d", len(videos), len(results))
+	}
+	for i, result := range results {
+		if result.Video.VideoID != videos[i].VideoID {
+			t.Errorf("Result %d is for %s, want %s", i, result.Video.VideoID, videos[i].VideoID)
+		}
+		if result.Status != statusSkipped {
+			t.Errorf("Result %d status = %s, want %s", i, result.Status, statusSkipped)
+		}
+	}
+	if len(downloader.calls()) != 0 {
+		t.Errorf("Expected no downloads, got %v", downloader.calls())
+	}
+}
+
+// fakeDownloader records the videos it is asked to download and fails those
+// listed in fail
+type fakeDownloader struct {
+	mu         sync.Mutex
+	fail       map[string]bool
+	downloaded []string
+}
+
+func (d *fakeDownloader) Name() string {
+	return "fake"
+}
+
+func (d *fakeDownloader) Download(_ context.Context, video VideoRef, _, _ io.Writer) (string, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.downloaded = append(d.downloaded, video.VideoID)
+	if d.fail[video.VideoID] {
+		return "", errors.New("download failed")
+	}
+	return video.VideoID + ".mp4", nil
+}
+
+func (d *fakeDownloader) calls() []string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return append([]string(nil), d.downloaded...)
+}
+
+func TestDownloadAll_UsesDownloader(t *testing.T) {
+	tests := []struct {
+		name         string
+		downloader   string
+		wantStatus   string
+		wantArchived []string
+	}{
+		{name: "records successful downloads", downloader: downloaderAuto, wantStatus: statusDownloaded, wantArchived: []string{"a", "c"}},
+		{name: "dry run records nothing", downloader: downloaderDryRun, wantStatus: statusWouldDownload},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			archivePath := filepath.Join(t.TempDir(), "archive")
+			archive, err := openArchive(archivePath)
+			if err != nil {
+				t.Fatalf("openArchive() error = %v", err)
+			}
+
+			videos := []VideoRef{loomRef("a"), loomRef("b"), loomRef("c")}
+			downloader := &fakeDownloader{fail: map[string]bool{"b": true}}
+			config := Config{OutputDir: t.TempDir(), Concurrency: 2, Downloader: tt.downloader}
+			results := downloadAll(context.Background(), videos, downloader, config, archive)
+
+			wantStatus := []string{tt.wantStatus, statusFailed, tt.wantStatus}
+			for i, result := range results {
+				if result.Status != wantStatus[i] {
+					t.Errorf("Result %d status = %s, want %s", i, result.Status, wantStatus[i])
+				}
+			}
+			if len(downloader.calls()) != len(videos) {
+				t.Errorf("Expected %d downloads, got %v", len(videos), downloader.calls())
+			}
+
+			reopened, err := openArchive(archivePath)
+			if err != nil {
+				t.Fatalf("openArchive() error = %v", err)
+			}
+			for _, id := range []string{"a", "b", "c"} {
+				want := slices.Contains(tt.wantArchived, id)
+				if reopened.Has(loomRef(id)) != want {
+					t.Errorf("Has(%q) = %v, want %v", id, !want, want)
+				}
+			}
+		})
+	}
+}
+
+func TestDownloadAll_RetriesTransientErrors(t *testing.T) {
+	archive, err := openArchive(filepath.Join(t.TempDir(), "archive"))
+	if err != nil {
+		t.Fatalf("openArchive() error = %v", err)
+	}
+
+	attempts := 0
+	downloader := downloaderFunc(func(video VideoRef) error {
+		attempts++
+		if attempts < 3 {
+			return &httpStatusError{URL: video.URL, StatusCode: 503}
+		}
+		return nil
+	})
+
+	config := Config{OutputDir: t.TempDir(), MaxAttempts: 3, RetryDelay: time.Millisecond}
+	results := downloadAll(context.Background(), []VideoRef{{VideoID: "a"}}, downloader, config, archive)
+
+	if results[0].Status != statusDownloaded {
+		t.Errorf("Status = %s, want %s (error %v)", results[0].Status, statusDownloaded, results[0].Err)
+	}
+	if attempts != 3 {
+		t.Errorf("Expected 3 attempts, got %d", attempts)
+	}
+}
+
+// downloaderFunc adapts a function to the Downloader interface
+type downloaderFunc func(video VideoRef) error
+
+func (f downloaderFunc) Name() string {
+	return "func"
+}
+
+func (f downloaderFunc) Download(_ context.Context, video VideoRef, _, _ io.Writer) (string, error) {
+	return "", f(video)
+}
+
+func TestFallbackDownloader(t *testing.T) {
+	primary := &fakeDownloader{fail: map[string]bool{"b": true}}
+	fallback := &fakeDownloader{}
+	downloader := fallbackDownloader{primary: primary, fallback: fallback}
+
+	var out bytes.Buffer
+	for _, id := range []string{"a", "b"} {
+		if _, err := downloader.Download(context.Background(), VideoRef{VideoID: id}, &out, &out); err != nil {
+			t.Errorf("Download(%q) error = %v", id, err)
+		}
+	}
+
+	if got := primary.calls(); !slices.Equal(got, []string{"a", "b"}) {
+		t.Errorf("Primary downloads = %v, want [a b]", got)
+	}
+	if got := fallback.calls(); !slices.Equal(got, []string{"b"}) {
+		t.Errorf("Fallback downloads = %v, want [b]", got)
+	}
+}
+
+func TestDryRunDownloader(t *testing.T) {
+	video := VideoRef{VideoID: "abc", URL: loomShareURL("abc")}
+	var out bytes.Buffer
+	path, err := dryRunDownloader{outputDir: "out"}.Download(context.Background(), video, &out, &out)
+	if err != nil {
+		t.Fatalf("Download() error = %v", err)
+	}
+	if path != "" {
+		t.Errorf("Expected no path, got %q", path)
+	}
+	if !strings.Contains(out.String(), video.URL) {
+		t.Errorf("Expected output to mention %s, got %q", video.URL, out.String())
+	}
+}
+
+func TestNewDownloader(t *testing.T) {
+	t.Setenv("PATH", t.TempDir())
+
+	tests := []struct {
+		downloader string
+		wantName   string
+		wantErr    bool
+	}{
+		{downloader: downloaderAuto, wantName: downloaderNative},
+		{downloader: downloaderNative, wantName: downloaderNative},
+		{downloader: downloaderDryRun, wantName: downloaderDryRun},
+		{downloader: downloaderYtDlp, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.downloader, func(t *testing.T) {
+			d, err := newDownloader(Config{Downloader: tt.downloader})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("newDownloader() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && d.Name() != tt.wantName {
+				t.Errorf("Name() = %q, want %q", d.Name(), tt.wantName)
+			}
+		})
+	}
+}
+
+func TestProviderDownloader(t *testing.T) {
+	loom := &fakeDownloader{}
+	skool := &fakeDownloader{}
+	others := &fakeDownloader{}
+	downloader := providerDownloader{loom: loom, skool: skool, others: others}
+
+	var out bytes.Buffer
+	for _, video := range []VideoRef{loomRef("a"), {Provider: providerYouTube, VideoID: "b"}, loomRef("c"), {Provider: providerVimeo, VideoID: "d"}, {Provider: providerSkool, VideoID: "e"}} {
+		if _, err := downloader.Download(context.Background(), video, &out, &out); err != nil {
+			t.Errorf("Download(%q) error = %v", video.VideoID, err)
+		}
+	}
+
+	if got := loom.calls(); !slices.Equal(got, []string{"a", "c"}) {
+		t.Errorf("Loom downloads = %v, want [a c]", got)
+	}
+	if got := others.calls(); !slices.Equal(got, []string{"b", "d"}) {
+		t.Errorf("Other downloads = %v, want [b d]", got)
+	}
+	if got := skool.calls(); !slices.Equal(got, []string{"e"}) {
+		t.Errorf("Skool downloads = %v, want [e]", got)
+	}
+}
+
+func TestNewDownloader_OtherHostsWithoutYtDlp(t *testing.T) {
+	t.Setenv("PATH", t.TempDir())
+
+	d, err := newDownloader(Config{Downloader: downloaderAuto})
+	if err != nil {
+		t.Fatalf("newDownloader() error = %v", err)
+	}
+	_, err = d.Download(context.Background(), VideoRef{Provider: providerYouTube, VideoID: "dQw4w9WgXcQ"}, io.Discard, io.Discard)
+	if !errors.Is(err, errUnsupportedProvider) || isRetryable(err) {
+		t.Errorf("Download() error = %v, want a permanent errUnsupportedProvider", err)
+	}
+}
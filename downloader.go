@@ -0,0 +1,197 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+
+	"github.com/chromedp/cdproto/network"
+)
+
+// errUnsupportedProvider is returned for videos of a host that none of the
+// available backends can download
+var errUnsupportedProvider = errors.New("unsupported video host")
+
+// Backends selectable with -downloader
+const (
+	downloaderAuto   = "auto"
+	downloaderNative = "native"
+	downloaderYtDlp  = "yt-dlp"
+	downloaderDryRun = "dry-run"
+)
+
+// Downloader fetches a single video. Download writes progress to stdout and
+// stderr and returns the path of the saved file, or "" when the backend does
+// not know it.
+type Downloader interface {
+	Name() string
+	Download(ctx context.Context, video VideoRef, stdout, stderr io.Writer) (string, error)
+}
+
+func isDownloaderName(name string) bool {
+	switch name {
+	case downloaderAuto, downloaderNative, downloaderYtDlp, downloaderDryRun:
+		return true
+	}
+	return false
+}
+
+// newDownloader returns the backend selected by config.Downloader. The auto
+// backend downloads Loom videos and videos uploaded to Skool natively, falling
+// back to yt-dlp when it is installed, and the videos of other hosts with
+// yt-dlp.
+func newDownloader(config Config) (Downloader, error) {
+	ytDlp := ytDlpDownloader{
+		cookiesFile: config.CookiesFile,
+		outputDir:   config.OutputDir,
+		newline:     config.Concurrency > 1,
+	}
+	_, err := exec.LookPath("yt-dlp")
+	haveYtDlp := err == nil
+
+	switch config.Downloader {
+	case downloaderYtDlp:
+		if !haveYtDlp {
+			return nil, fmt.Errorf("yt-dlp not found in PATH")
+		}
+		return ytDlp, nil
+	case downloaderDryRun:
+		return dryRunDownloader{outputDir: config.OutputDir}, nil
+	}
+
+	var cookies []*network.CookieParam
+	if config.CookiesFile != "" {
+		if cookies, err = parseCookiesFile(config.CookiesFile); err != nil {
+			return nil, fmt.Errorf("error reading cookies for native downloads: %v", err)
+		}
+	}
+	native := nativeDownloader{client: newLoomClient(cookies), outputDir: config.OutputDir}
+	skoolNative := skoolDownloader{client: newSkoolClient(cookies), outputDir: config.OutputDir}
+
+	// Other hosts have no native backend
+	var loom, skool, others Downloader = native, skoolNative, ytDlp
+	switch {
+	case config.Downloader == downloaderNative:
+		others = unsupportedDownloader{reason: "use -downloader=auto or -downloader=yt-dlp"}
+	case !haveYtDlp:
+		others = unsupportedDownloader{reason: "install yt-dlp to download them"}
+	default:
+		loom = fallbackDownloader{primary: native, fallback: ytDlp}
+		skool = fallbackDownloader{primary: skoolNative, fallback: ytDlp}
+	}
+	return providerDownloader{loom: loom, skool: skool, others: others}, nil
+}
+
+// providerDownloader routes each video to the backend for its host
+type providerDownloader struct {
+	loom   Downloader
+	skool  Downloader
+	others Downloader
+}
+
+func (d providerDownloader) Name() string {
+	return d.loom.Name()
+}
+
+func (d providerDownloader) Download(ctx context.Context, video VideoRef, stdout, stderr io.Writer) (string, error) {
+	switch video.Provider {
+	case providerLoom:
+		return d.loom.Download(ctx, video, stdout, stderr)
+	case providerSkool:
+		return d.skool.Download(ctx, video, stdout, stderr)
+	}
+	return d.others.Download(ctx, video, stdout, stderr)
+}
+
+// unsupportedDownloader fails every download, for hosts no available backend
+// can download
+type unsupportedDownloader struct {
+	reason string
+}
+
+func (d unsupportedDownloader) Name() string {
+	return "unsupported"
+}
+
+func (d unsupportedDownloader) Download(_ context.Context, video VideoRef, _, _ io.Writer) (string, error) {
+	return "", permanent(fmt.Errorf("%w: %s videos need yt-dlp, %s", errUnsupportedProvider, providerLabel(video.Provider), d.reason))
+}
+
+// nativeDownloader downloads Loom videos directly over HTTP
+type nativeDownloader struct {
+	client    *loomClient
+	outputDir string
+}
+
+func (d nativeDownloader) Name() string {
+	return downloaderNative
+}
+
+func (d nativeDownloader) Download(ctx context.Context, video VideoRef, stdout, _ io.Writer) (string, error) {
+	return downloadNative(ctx, d.client, video, d.outputDir, stdout)
+}
+
+// skoolDownloader downloads videos uploaded to Skool directly over HTTP
+type skoolDownloader struct {
+	client    *skoolClient
+	outputDir string
+}
+
+func (d skoolDownloader) Name() string {
+	return downloaderNative
+}
+
+func (d skoolDownloader) Download(ctx context.Context, video VideoRef, stdout, _ io.Writer) (string, error) {
+	return downloadSkoolVideo(ctx, d.client, video, d.outputDir, stdout)
+}
+
+// ytDlpDownloader downloads videos by running yt-dlp
+type ytDlpDownloader struct {
+	cookiesFile string
+	outputDir   string
+	newline     bool
+}
+
+func (d ytDlpDownloader) Name() string {
+	return downloaderYtDlp
+}
+
+func (d ytDlpDownloader) Download(ctx context.Context, video VideoRef, stdout, stderr io.Writer) (string, error) {
+	return downloadWithYtDlp(ctx, video, d.cookiesFile, d.outputDir, d.newline, stdout, stderr)
+}
+
+// dryRunDownloader only reports what would be downloaded and where
+type dryRunDownloader struct {
+	outputDir string
+}
+
+func (d dryRunDownloader) Name() string {
+	return downloaderDryRun
+}
+
+func (d dryRunDownloader) Download(_ context.Context, video VideoRef, stdout, _ io.Writer) (string, error) {
+	_, _ = fmt.Fprintf(stdout, "[dry-run] Would download %s to %s\n", video.URL, outputTemplate(d.outputDir, video))
+	return "", nil
+}
+
+// fallbackDownloader tries primary first and retries failed downloads with fallback
+type fallbackDownloader struct {
+	primary  Downloader
+	fallback Downloader
+}
+
+func (d fallbackDownloader) Name() string {
+	return d.primary.Name() + "+" + d.fallback.Name()
+}
+
+func (d fallbackDownloader) Download(ctx context.Context, video VideoRef, stdout, stderr io.Writer) (string, error) {
+	path, err := d.primary.Download(ctx, video, stdout, stderr)
+	if err == nil || ctx.Err() != nil {
+		return path, err
+	}
+
+	_, _ = fmt.Fprintf(stdout, "⚠️ %s download failed (%v), falling back to %s\n", d.primary.Name(), err, d.fallback.Name())
+	return d.fallback.Download(ctx, video, stdout, stderr)
+}
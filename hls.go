@@ -0,0 +1,295 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// hlsPlaylist is the parsed form of an HLS master or media playlist
+type hlsPlaylist struct {
+	Variants []hlsVariant   // set for master playlists
+	Audio    []hlsRendition // separate audio renditions of master playlists
+	InitURL  string         // EXT-X-MAP initialization segment of fMP4 streams
+	Segments []string
+	KeyURL   string // set when segments are encrypted
+	// AudioURL is the playlist of the audio that goes with a media playlist
+	// reached through a master playlist, when the audio is not muxed in
+	AudioURL string
+}
+
+// hlsVariant is one rendition listed in a master playlist
+type hlsVariant struct {
+	URL        string
+	Bandwidth  int
+	AudioGroup string
+}
+
+// hlsRendition is an alternative audio rendition listed in a master playlist
+type hlsRendition struct {
+	GroupID string
+	URL     string
+	Default bool
+}
+
+// parseHLSPlaylist parses an m3u8 playlist, resolving every URI against base.
+// Segment and variant URIs without their own query inherit the query of
+// base, which carries the signature Loom's CDN expects on every request.
+func parseHLSPlaylist(content string, base *url.URL) (*hlsPlaylist, error) {
+	scanner := bufio.NewScanner(strings.NewReader(content))
+	if !scanner.Scan() || strings.TrimSpace(scanner.Text()) != "#EXTM3U" {
+		return nil, fmt.Errorf("not an HLS playlist")
+	}
+
+	playlist := &hlsPlaylist{}
+	var pendingVariant *hlsVariant
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case line == "":
+			continue
+		case strings.HasPrefix(line, "#EXT-X-STREAM-INF:"):
+			attrs := parseHLSAttributes(strings.TrimPrefix(line, "#EXT-X-STREAM-INF:"))
+			bandwidth, _ := strconv.Atoi(attrs["BANDWIDTH"])
+			pendingVariant = &hlsVariant{Bandwidth: bandwidth, AudioGroup: attrs["AUDIO"]}
+		case strings.HasPrefix(line, "#EXT-X-MEDIA:"):
+			attrs := parseHLSAttributes(strings.TrimPrefix(line, "#EXT-X-MEDIA:"))
+			// Renditions without a URI are muxed into the variants
+			if attrs["TYPE"] == "AUDIO" && attrs["URI"] != "" {
+				playlist.Audio = append(playlist.Audio, hlsRendition{
+					GroupID: attrs["GROUP-ID"],
+					URL:     resolveMediaURL(base, attrs["URI"]),
+					Default: attrs["DEFAULT"] == "YES",
+				})
+			}
+		case strings.HasPrefix(line, "#EXT-X-MAP:"):
+			attrs := parseHLSAttributes(strings.TrimPrefix(line, "#EXT-X-MAP:"))
+			playlist.InitURL = resolveMediaURL(base, attrs["URI"])
+		case strings.HasPrefix(line, "#EXT-X-KEY:"):
+			attrs := parseHLSAttributes(strings.TrimPrefix(line, "#EXT-X-KEY:"))
+			if attrs["METHOD"] != "" && attrs["METHOD"] != "NONE" {
+				playlist.KeyURL = resolveMediaURL(base, attrs["URI"])
+			}
+		case strings.HasPrefix(line, "#"):
+			continue
+		case pendingVariant != nil:
+			pendingVariant.URL = resolveMediaURL(base, line)
+			playlist.Variants = append(playlist.Variants, *pendingVariant)
+			pendingVariant = nil
+		default:
+			playlist.Segments = append(playlist.Segments, resolveMediaURL(base, line))
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return playlist, nil
+}
+
+// parseHLSAttributes parses an attribute list such as
+// BANDWIDTH=1280000,CODECS="avc1.4d401f,mp4a.40.2"
+func parseHLSAttributes(s string) map[string]string {
+	attrs := make(map[string]string)
+	for s != "" {
+		eq := strings.IndexByte(s, '=')
+		if eq < 0 {
+			break
+		}
+		key := strings.TrimSpace(s[:eq])
+		s = s[eq+1:]
+
+		var value string
+		if strings.HasPrefix(s, `"`) {
+			end := strings.IndexByte(s[1:], '"')
+			if end < 0 {
+				value, s = s[1:], ""
+			} else {
+				value, s = s[1:end+1], s[end+2:]
+			}
+		} else if comma := strings.IndexByte(s, ','); comma >= 0 {
+			value, s = s[:comma], s[comma:]
+		} else {
+			value, s = s, ""
+		}
+
+		attrs[key] = value
+		s = strings.TrimPrefix(s, ",")
+	}
+	return attrs
+}
+
+func resolveMediaURL(base *url.URL, ref string) string {
+	u, err := base.Parse(ref)
+	if err != nil {
+		return ref
+	}
+	if u.RawQuery == "" {
+		u.RawQuery = base.RawQuery
+	}
+	return u.String()
+}
+
+// bestVariant returns the variant with the highest bandwidth
+func (p *hlsPlaylist) bestVariant() hlsVariant {
+	best := p.Variants[0]
+	for _, v := range p.Variants[1:] {
+		if v.Bandwidth > best.Bandwidth {
+			best = v
+		}
+	}
+	return best
+}
+
+// audioURL returns the playlist of the separate audio rendition of a variant,
+// preferring the default one of its group, or "" when its audio is muxed in
+func (p *hlsPlaylist) audioURL(v hlsVariant) string {
+	var result string
+	for _, rendition := range p.Audio {
+		if v.AudioGroup == "" || rendition.GroupID != v.AudioGroup {
+			continue
+		}
+		if rendition.Default {
+			return rendition.URL
+		}
+		if result == "" {
+			result = rendition.URL
+		}
+	}
+	return result
+}
+
+// fetchHLSPlaylist downloads and parses the media playlist at playlistURL,
+// following a master playlist to its best variant and its audio
+func fetchHLSPlaylist(ctx context.Context, client mediaFetcher, playlistURL string) (*hlsPlaylist, error) {
+	var audioURL string
+	for depth := 0; depth < 2; depth++ {
+		base, err := url.Parse(playlistURL)
+		if err != nil {
+			return nil, err
+		}
+
+		resp, err := client.get(ctx, playlistURL)
+		if err != nil {
+			return nil, err
+		}
+		content, err := io.ReadAll(resp.Body)
+		_ = resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		playlist, err := parseHLSPlaylist(string(content), base)
+		if err != nil {
+			return nil, err
+		}
+		if len(playlist.Variants) == 0 {
+			playlist.AudioURL = audioURL
+			return playlist, nil
+		}
+		best := playlist.bestVariant()
+		audioURL = playlist.audioURL(best)
+		playlistURL = best.URL
+	}
+	return nil, fmt.Errorf("too many nested HLS playlists")
+}
+
+// downloadHLS downloads every segment of the HLS stream at playlistURL and
+// returns the path of the saved file. Segments are concatenated, which yields
+// an MPEG-TS file, or an MP4 file for fMP4 streams. MPEG-TS output is remuxed
+// into MP4 when ffmpeg is installed. A separate audio rendition is downloaded
+// as well and muxed with the video. outputPath maps a file extension to the
+// target path.
+func downloadHLS(ctx context.Context, client mediaFetcher, playlistURL string, outputPath func(ext string) string, out io.Writer) (string, error) {
+	playlist, err := fetchHLSPlaylist(ctx, client, playlistURL)
+	if err != nil {
+		return "", fmt.Errorf("error reading HLS playlist: %w", err)
+	}
+	segments, ext, err := playlist.mediaSegments()
+	if err != nil {
+		return "", err
+	}
+	if playlist.AudioURL != "" {
+		return downloadHLSWithAudio(ctx, client, segments, ext, playlist.AudioURL, outputPath, out)
+	}
+
+	stream := outputPath(ext)
+	if err := downloadSegments(ctx, client, segments, stream, out); err != nil {
+		return "", err
+	}
+
+	ffmpeg, err := exec.LookPath("ffmpeg")
+	if ext == "mp4" || err != nil {
+		return stream, nil
+	}
+
+	target := outputPath("mp4")
+	_, _ = fmt.Fprintf(out, "[native] Muxing into %s\n", target)
+	if err := muxToMP4(ctx, ffmpeg, []string{stream}, target); err != nil {
+		// The MPEG-TS file is complete and playable, so keep it
+		_, _ = fmt.Fprintf(out, "⚠️ %v, keeping %s\n", err, stream)
+		return stream, nil
+	}
+	_ = os.Remove(stream)
+	return target, nil
+}
+
+// mediaSegments returns the URLs to download for a media playlist, starting
+// with its initialization segment, and the extension of the joined stream
+func (p *hlsPlaylist) mediaSegments() ([]string, string, error) {
+	if p.KeyURL != "" {
+		return nil, "", fmt.Errorf("%w: encrypted HLS", errUnsupportedStream)
+	}
+	if len(p.Segments) == 0 {
+		return nil, "", fmt.Errorf("HLS playlist has no segments")
+	}
+	if p.InitURL != "" {
+		return append([]string{p.InitURL}, p.Segments...), "mp4", nil
+	}
+	return p.Segments, "ts", nil
+}
+
+// downloadHLSWithAudio downloads the video segments of an HLS stream and its
+// separate audio rendition, and muxes them into an MP4 file. Without ffmpeg
+// the stream is unsupported, since the video alone would have no sound.
+func downloadHLSWithAudio(ctx context.Context, client mediaFetcher, videoSegments []string, videoExt, audioURL string, outputPath func(ext string) string, out io.Writer) (string, error) {
+	ffmpeg, err := exec.LookPath("ffmpeg")
+	if err != nil {
+		return "", fmt.Errorf("%w: HLS with separate audio needs ffmpeg", errUnsupportedStream)
+	}
+
+	audio, err := fetchHLSPlaylist(ctx, client, audioURL)
+	if err != nil {
+		return "", fmt.Errorf("error reading HLS audio playlist: %w", err)
+	}
+	audioSegments, audioExt, err := audio.mediaSegments()
+	if err != nil {
+		return "", fmt.Errorf("audio track: %w", err)
+	}
+
+	target := outputPath("mp4")
+	stem := strings.TrimSuffix(target, ".mp4")
+	videoPath, audioPath := stem+".video."+videoExt, stem+".audio."+audioExt
+	_, _ = fmt.Fprintln(out, "[native] Downloading video track")
+	if err := downloadSegments(ctx, client, videoSegments, videoPath, out); err != nil {
+		return "", fmt.Errorf("video track: %w", err)
+	}
+	_, _ = fmt.Fprintln(out, "[native] Downloading audio track")
+	if err := downloadSegments(ctx, client, audioSegments, audioPath, out); err != nil {
+		return "", fmt.Errorf("audio track: %w", err)
+	}
+
+	_, _ = fmt.Fprintf(out, "[native] Muxing into %s\n", target)
+	if err := muxToMP4(ctx, ffmpeg, []string{videoPath, audioPath}, target); err != nil {
+		return "", err
+	}
+	_ = os.Remove(videoPath)
+	_ = os.Remove(audioPath)
+	return target, nil
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"net/url"
+	"reflect"
+	"testing"
+)
+
+func TestParseHLSPlaylist_Master(t *testing.T) {
+	base, _ := url.Parse("https://cdn.example.com/video/master.m3u8?sig=abc")
+	content := `#EXTM3U
+#EXT-X-STREAM-INF:BANDWIDTH=500000,CODECS="avc1.4d401f,mp4a.40.2"
+low/index.m3u8
+#EXT-X-STREAM-INF:BANDWIDTH=2000000,CODECS="avc1.640028,mp4a.40.2"
+https://other.example.com/high.m3u8?token=1
+`
+
+	playlist, err := parseHLSPlaylist(content, base)
+	if err != nil {
+		t.Fatalf("parseHLSPlaylist() error = %v", err)
+	}
+
+	expected := []hlsVariant{
+		{URL: "https://cdn.example.com/video/low/index.m3u8?sig=abc", Bandwidth: 500000},
+		{URL: "https://other.example.com/high.m3u8?token=1", Bandwidth: 2000000},
+	}
+	if !reflect.DeepEqual(playlist.Variants, expected) {
+		t.Errorf("Variants = %v, want %v", playlist.Variants, expected)
+	}
+	if best := playlist.bestVariant(); best.Bandwidth != 2000000 {
+		t.Errorf("bestVariant() = %v, want the 2000000 variant", best)
+	}
+}
+
+func TestParseHLSPlaylist_AudioGroup(t *testing.T) {
+	base, _ := url.Parse("https://cdn.example.com/video/master.m3u8?sig=abc")
+	content := `#EXTM3U
+#EXT-X-MEDIA:TYPE=AUDIO,GROUP-ID="aud-low",NAME="English",DEFAULT=YES,URI="audio/low.m3u8"
+#EXT-X-MEDIA:TYPE=AUDIO,GROUP-ID="aud-high",NAME="Commentary",DEFAULT=NO,URI="audio/commentary.m3u8"
+#EXT-X-MEDIA:TYPE=AUDIO,GROUP-ID="aud-high",NAME="English",DEFAULT=YES,URI="audio/high.m3u8"
+#EXT-X-MEDIA:TYPE=SUBTITLES,GROUP-ID="subs",NAME="English",URI="subs.m3u8"
+#EXT-X-STREAM-INF:BANDWIDTH=500000,AUDIO="aud-low"
+low.m3u8
+#EXT-X-STREAM-INF:BANDWIDTH=2000000,AUDIO="aud-high"
+high.m3u8
+#EXT-X-STREAM-INF:BANDWIDTH=100000
+muxed.m3u8
+`
+
+	playlist, err := parseHLSPlaylist(content, base)
+	if err != nil {
+		t.Fatalf("parseHLSPlaylist() error = %v", err)
+	}
+	if len(playlist.Audio) != 3 {
+		t.Fatalf("Expected 3 audio renditions, got %v", playlist.Audio)
+	}
+
+	best := playlist.bestVariant()
+	if best.AudioGroup != "aud-high" {
+		t.Errorf("bestVariant() = %v, want the aud-high variant", best)
+	}
+	if audio := playlist.audioURL(best); audio != "https://cdn.example.com/video/audio/high.m3u8?sig=abc" {
+		t.Errorf("audioURL() = %q, want the default rendition of the group", audio)
+	}
+	if audio := playlist.audioURL(playlist.Variants[2]); audio != "" {
+		t.Errorf("audioURL() of a variant with muxed audio = %q, want none", audio)
+	}
+}
+
+func TestParseHLSPlaylist_Media(t *testing.T) {
+	base, _ := url.Parse("https://cdn.example.com/video/high.m3u8?sig=abc")
+	content := `#EXTM3U
+#EXT-X-VERSION:7
+#EXT-X-MAP:URI="init.mp4"
+#EXTINF:4.0,
+seg0.m4s
+
+#EXTINF:4.0,
+/abs/seg1.m4s
+#EXT-X-ENDLIST
+`
+
+	playlist, err := parseHLSPlaylist(content, base)
+	if err != nil {
+		t.Fatalf("parseHLSPlaylist() error = %v", err)
+	}
+
+	if playlist.InitURL != "https://cdn.example.com/video/init.mp4?sig=abc" {
+		t.Errorf("InitURL = %q", playlist.InitURL)
+	}
+	expected := []string{
+		"https://cdn.example.com/video/seg0.m4s?sig=abc",
+		"https://cdn.example.com/abs/seg1.m4s?sig=abc",
+	}
+	if !reflect.DeepEqual(playlist.Segments, expected) {
+		t.Errorf("Segments = %v, want %v", playlist.Segments, expected)
+	}
+	if playlist.KeyURL != "" {
+		t.Errorf("Expected unencrypted playlist, got key %q", playlist.KeyURL)
+	}
+}
+
+func TestParseHLSPlaylist_Encrypted(t *testing.T) {
+	base, _ := url.Parse("https://cdn.example.com/video/high.m3u8")
+	content := "#EXTM3U\n#EXT-X-KEY:METHOD=AES-128,URI=\"key.bin\"\n#EXTINF:4.0,\nseg0.ts\n"
+
+	playlist, err := parseHLSPlaylist(content, base)
+	if err != nil {
+		t.Fatalf("parseHLSPlaylist() error = %v", err)
+	}
+	if playlist.KeyURL != "https://cdn.example.com/video/key.bin" {
+		t.Errorf("KeyURL = %q", playlist.KeyURL)
+	}
+}
+
+func TestParseHLSPlaylist_Invalid(t *testing.T) {
+	base, _ := url.Parse("https://cdn.example.com/video/high.m3u8")
+	if _, err := parseHLSPlaylist("<html></html>", base); err == nil {
+		t.Error("Expected error for non-playlist content, got nil")
+	}
+}
+
+func TestParseHLSAttributes(t *testing.T) {
+	attrs := parseHLSAttributes(`BANDWIDTH=1280000,CODECS="avc1.4d401f,mp4a.40.2",RESOLUTION=1280x720`)
+
+	expected := map[string]string{
+		"BANDWIDTH":  "1280000",
+		"CODECS":     "avc1.4d401f,mp4a.40.2",
+		"RESOLUTION": "1280x720",
+	}
+	if !reflect.DeepEqual(attrs, expected) {
+		t.Errorf("parseHLSAttributes() = %v, want %v", attrs, expected)
+	}
+}
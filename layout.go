@@ -0,0 +1,92 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"unicode"
+)
+
+const maxPathComponentLength = 100
+
+// videoDir returns the folder a video is saved in:
+// <output>/<community>/<course>/<NN - module>. Videos scraped outside of a
+// course crawl have no course and are saved directly in the output directory.
+func videoDir(outputDir string, video VideoRef) string {
+	if video.Course == "" {
+		return outputDir
+	}
+
+	dir := filepath.Join(outputDir, sanitizePathComponent(video.Community), sanitizePathComponent(video.Course))
+	if video.Module != "" {
+		dir = filepath.Join(dir, fmt.Sprintf("%02d - %s", video.ModuleIndex, sanitizePathComponent(video.Module)))
+	}
+	return dir
+}
+
+// outputTemplate returns the yt-dlp output template for a video, named
+// <NN - lesson> - <title> [<id>].<ext> inside its lesson folder
+func outputTemplate(outputDir string, video VideoRef) string {
+	title := "%(title)s"
+	if video.Provider == providerSkool {
+		// yt-dlp names bare HLS streams after the playlist file
+		title = escapeTemplate(sanitizePathComponent(skoolVideoTitle(video)))
+	}
+	name := escapeTemplate(lessonPrefix(video)) + title + escapeTemplate(idSuffix(video)) + ".%(ext)s"
+	return filepath.Join(escapeTemplate(videoDir(outputDir, video)), name)
+}
+
+// nativeOutputPath returns the path the native downloader saves a video to,
+// matching the names yt-dlp produces from outputTemplate
+func nativeOutputPath(outputDir string, video VideoRef, title, ext string) string {
+	name := lessonPrefix(video) + sanitizePathComponent(title) + idSuffix(video) + "." + ext
+	return filepath.Join(videoDir(outputDir, video), name)
+}
+
+// idSuffix returns the " [<id>]" file name suffix that keeps videos with the
+// same title apart
+func idSuffix(video VideoRef) string {
+	if video.VideoID == "" {
+		return ""
+	}
+	return " [" + sanitizePathComponent(video.VideoID) + "]"
+}
+
+// lessonPrefix returns the "<NN - lesson> - " file name prefix for videos
+// found during a course crawl
+func lessonPrefix(video VideoRef) string {
+	if video.Course == "" {
+		return ""
+	}
+	return fmt.Sprintf("%02d - %s - ", video.LessonIndex, sanitizePathComponent(video.LessonTitle))
+}
+
+// sanitizePathComponent makes a Skool title safe to use as a file or folder
+// name on every platform
+func sanitizePathComponent(name string) string {
+	name = strings.Map(func(r rune) rune {
+		switch {
+		case strings.ContainsRune(`/\:*?"<>|`, r):
+			return '_'
+		case unicode.IsControl(r):
+			return ' '
+		}
+		return r
+	}, name)
+
+	name = strings.Join(strings.Fields(name), " ")
+	if runes := []rune(name); len(runes) > maxPathComponentLength {
+		name = string(runes[:maxPathComponentLength])
+	}
+	name = strings.Trim(name, " .")
+
+	if name == "" {
+		return "Untitled"
+	}
+	return name
+}
+
+// escapeTemplate escapes literal percent signs for use in a yt-dlp output template
+func escapeTemplate(s string) string {
+	return strings.ReplaceAll(s, "%", "%%")
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestSanitizePathComponent(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{"Plain title", "Getting Started", "Getting Started"},
+		{"Reserved characters", `What/Why: "A" <vs> B?`, `What_Why_ _A_ _vs_ B_`},
+		{"Whitespace and control characters", "  Line one\nLine\ttwo  ", "Line one Line two"},
+		{"Trailing dots", "Chapter 1...", "Chapter 1"},
+		{"Empty", "   ", "Untitled"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result := sanitizePathComponent(tt.input); result != tt.expected {
+				t.Errorf("sanitizePathComponent(%q) = %q, want %q", tt.input, result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestOutputTemplate(t *testing.T) {
+	tests := []struct {
+		name     string
+		video    VideoRef
+		expected string
+	}{
+		{
+			name:     "Single page",
+			video:    VideoRef{VideoID: "a", Community: "school"},
+			expected: filepath.Join("out", "%(title)s [a].%(ext)s"),
+		},
+		{
+			name: "Lesson in module",
+			video: VideoRef{
+				VideoID: "abc123", Community: "school", Course: "Course A", Module: "Basics", ModuleIndex: 2,
+				LessonTitle: "Setup", LessonIndex: 3,
+			},
+			expected: filepath.Join("out", "school", "Course A", "02 - Basics", "03 - Setup - %(title)s [abc123].%(ext)s"),
+		},
+		{
+			name:     "Lesson without module",
+			video:    VideoRef{Community: "school", Course: "Course A", LessonTitle: "Intro", LessonIndex: 1},
+			expected: filepath.Join("out", "school", "Course A", "01 - Intro - %(title)s.%(ext)s"),
+		},
+		{
+			name:     "Percent sign is escaped",
+			video:    VideoRef{Community: "school", Course: "100% Growth", LessonTitle: "Save 50%", LessonIndex: 1},
+			expected: filepath.Join("out", "school", "100%% Growth", "01 - Save 50%% - %(title)s.%(ext)s"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result := outputTemplate("out", tt.video); result != tt.expected {
+				t.Errorf("outputTemplate() = %q, want %q", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestNativeOutputPath(t *testing.T) {
+	lesson := VideoRef{Community: "school", Course: "Course A", LessonTitle: "Intro", LessonIndex: 1}
+	first, second := lesson, lesson
+	first.VideoID, second.VideoID = "abc123", "def456"
+
+	expected := filepath.Join("out", "school", "Course A", "01 - Intro - Demo [abc123].mp4")
+	if result := nativeOutputPath("out", first, "Demo", "mp4"); result != expected {
+		t.Errorf("nativeOutputPath() = %q, want %q", result, expected)
+	}
+	// Videos with the same title in one lesson must not overwrite each other
+	if nativeOutputPath("out", first, "Demo", "mp4") == nativeOutputPath("out", second, "Demo", "mp4") {
+		t.Error("Expected different paths for videos with the same title")
+	}
+	if result := nativeOutputPath("out", VideoRef{}, "Demo", "ts"); result != filepath.Join("out", "Demo.ts") {
+		t.Errorf("nativeOutputPath() without ID = %q", result)
+	}
+}
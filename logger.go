@@ -0,0 +1,203 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// Values accepted by -log-level and -log-format
+const (
+	logLevelQuiet = "quiet"
+	logLevelInfo  = "info"
+	logLevelDebug = "debug"
+	logLevelTrace = "trace"
+
+	logFormatText = "text"
+	logFormatJSON = "json"
+)
+
+// levelTrace is below slog.LevelDebug and used for raw browser protocol traffic
+const levelTrace = slog.Level(-8)
+
+// parseLogLevel maps a -log-level name to a slog level. Quiet only shows
+// warnings and errors.
+func parseLogLevel(name string) (slog.Level, error) {
+	switch name {
+	case logLevelQuiet:
+		return slog.LevelWarn, nil
+	case logLevelInfo, "":
+		return slog.LevelInfo, nil
+	case logLevelDebug:
+		return slog.LevelDebug, nil
+	case logLevelTrace:
+		return levelTrace, nil
+	}
+	return 0, fmt.Errorf("unknown log level %q, use quiet, info, debug or trace", name)
+}
+
+func isLogFormat(format string) bool {
+	return format == "" || format == logFormatText || format == logFormatJSON
+}
+
+// newLogger returns a logger writing to w, as JSON lines or as the plain
+// console output people read in a terminal
+func newLogger(w io.Writer, level slog.Level, format string) *slog.Logger {
+	if format == logFormatJSON {
+		return slog.New(slog.NewJSONHandler(w, &slog.HandlerOptions{
+			Level:       level,
+			ReplaceAttr: replaceJSONAttr,
+		}))
+	}
+	return slog.New(&consoleHandler{out: w, level: level, mu: &sync.Mutex{}})
+}
+
+// replaceJSONAttr names the trace level and drops the emoji that lead
+// console messages, which only get in the way of log queries
+func replaceJSONAttr(groups []string, a slog.Attr) slog.Attr {
+	if len(groups) > 0 {
+		return a
+	}
+	switch a.Key {
+	case slog.LevelKey:
+		if level, ok := a.Value.Any().(slog.Level); ok && level <= levelTrace {
+			a.Value = slog.StringValue("TRACE")
+		}
+	case slog.MessageKey:
+		a.Value = slog.StringValue(stripEmoji(a.Value.String()))
+	}
+	return a
+}
+
+// stripEmoji removes the symbols and spaces leading a message
+func stripEmoji(msg string) string {
+	return strings.TrimLeftFunc(msg, func(r rune) bool {
+		return r > unicode.MaxASCII || unicode.IsSpace(r)
+	})
+}
+
+// consoleHandler writes each record as its message followed by its
+// attributes as key=value pairs, without timestamps or levels
+type consoleHandler struct {
+	out    io.Writer
+	level  slog.Level
+	mu     *sync.Mutex
+	attrs  []slog.Attr
+	prefix string // group prefix for attribute keys
+}
+
+func (h *consoleHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.level
+}
+
+func (h *consoleHandler) Handle(_ context.Context, r slog.Record) error {
+	var buf bytes.Buffer
+	buf.WriteString(r.Message)
+	for _, a := range h.attrs {
+		writeConsoleAttr(&buf, "", a)
+	}
+	r.Attrs(func(a slog.Attr) bool {
+		writeConsoleAttr(&buf, h.prefix, a)
+		return true
+	})
+	buf.WriteByte('\n')
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	_, err := h.out.Write(buf.Bytes())
+	return err
+}
+
+func writeConsoleAttr(buf *bytes.Buffer, prefix string, a slog.Attr) {
+	a.Value = a.Value.Resolve()
+	if a.Equal(slog.Attr{}) {
+		return
+	}
+	if a.Value.Kind() == slog.KindGroup {
+		for _, ga := range a.Value.Group() {
+			writeConsoleAttr(buf, prefix+a.Key+".", ga)
+		}
+		return
+	}
+
+	value := a.Value.String()
+	if strings.ContainsAny(value, " \t\"=") || value == "" {
+		quoted, _ := json.Marshal(value)
+		value = string(quoted)
+	}
+	_, _ = fmt.Fprintf(buf, " %s%s=%s", prefix, a.Key, value)
+}
+
+func (h *consoleHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	clone := *h
+	clone.attrs = append([]slog.Attr(nil), h.attrs...)
+	for _, a := range attrs {
+		a.Key = h.prefix + a.Key
+		clone.attrs = append(clone.attrs, a)
+	}
+	return &clone
+}
+
+func (h *consoleHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	clone := *h
+	clone.prefix = h.prefix + name + "."
+	return &clone
+}
+
+// logWriter turns lines written to it, such as yt-dlp output, into log
+// records at the given level. Progress updates separated by carriage returns
+// become records of their own.
+type logWriter struct {
+	logger *slog.Logger
+	level  slog.Level
+	buf    []byte
+}
+
+func newLogWriter(logger *slog.Logger, level slog.Level) *logWriter {
+	return &logWriter{logger: logger, level: level}
+}
+
+func (w *logWriter) Write(p []byte) (int, error) {
+	w.buf = append(w.buf, p...)
+	for {
+		i := bytes.IndexAny(w.buf, "\r\n")
+		if i < 0 {
+			break
+		}
+		w.log(w.buf[:i])
+		w.buf = w.buf[i+1:]
+	}
+	return len(p), nil
+}
+
+// Flush logs a trailing line that was not terminated
+func (w *logWriter) Flush() error {
+	w.log(w.buf)
+	w.buf = nil
+	return nil
+}
+
+func (w *logWriter) log(line []byte) {
+	if text := strings.TrimSpace(string(line)); text != "" {
+		w.logger.Log(context.Background(), w.level, text)
+	}
+}
+
+// chromedpLogf returns a printf-style logger for chromedp that logs at level
+func chromedpLogf(level slog.Level) func(string, ...any) {
+	return func(format string, args ...any) {
+		// Protocol messages are frequent, so only format enabled ones
+		if slog.Default().Enabled(context.Background(), level) {
+			slog.Log(context.Background(), level, fmt.Sprintf(format, args...), "source", "chromedp")
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestParseLogLevel(t *testing.T) {
+	tests := []struct {
+		name     string
+		expected slog.Level
+		wantErr  bool
+	}{
+		{name: "quiet", expected: slog.LevelWarn},
+		{name: "info", expected: slog.LevelInfo},
+		{name: "", expected: slog.LevelInfo},
+		{name: "debug", expected: slog.LevelDebug},
+		{name: "trace", expected: levelTrace},
+		{name: "verbose", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		level, err := parseLogLevel(tt.name)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseLogLevel(%q) error = %v, wantErr %v", tt.name, err, tt.wantErr)
+			continue
+		}
+		if err == nil && level != tt.expected {
+			t.Errorf("parseLogLevel(%q) = %v, want %v", tt.name, level, tt.expected)
+		}
+	}
+}
+
+func TestConsoleLogger(t *testing.T) {
+	var out bytes.Buffer
+	logger := newLogger(&out, slog.LevelInfo, logFormatText)
+
+	logger.With("worker", 2).Info("📥 Downloading", "url", "https://www.loom.com/share/abc", "lesson", "Module 1 › Intro")
+	logger.Debug("hidden")
+	logger.WithGroup("http").Warn("⚠️ Slow", "status", 503)
+
+	expected := "📥 Downloading worker=2 url=https://www.loom.com/share/abc lesson=\"Module 1 › Intro\"\n" +
+		"⚠️ Slow http.status=503\n"
+	if out.String() != expected {
+		t.Errorf("Unexpected console output:\n%q\nwant\n%q", out.String(), expected)
+	}
+}
+
+func TestJSONLogger(t *testing.T) {
+	var out bytes.Buffer
+	logger := newLogger(&out, levelTrace, logFormatJSON)
+
+	logger.Info("📍 Landed on", "url", "https://www.skool.com/school/classroom")
+	logger.Log(t.Context(), levelTrace, "protocol message")
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expected 2 JSON lines, got %d: %q", len(lines), out.String())
+	}
+
+	var first, second map[string]any
+	if err := json.Unmarshal([]byte(lines[0]), &first); err != nil {
+		t.Fatalf("Invalid JSON line %q: %v", lines[0], err)
+	}
+	if err := json.Unmarshal([]byte(lines[1]), &second); err != nil {
+		t.Fatalf("Invalid JSON line %q: %v", lines[1], err)
+	}
+
+	if first["msg"] != "Landed on" || first["level"] != "INFO" || first["url"] != "https://www.skool.com/school/classroom" {
+		t.Errorf("Unexpected first record: %v", first)
+	}
+	if second["level"] != "TRACE" {
+		t.Errorf("Expected TRACE level, got %v", second["level"])
+	}
+}
+
+func TestLogWriter(t *testing.T) {
+	var out bytes.Buffer
+	w := newLogWriter(newLogger(&out, slog.LevelDebug, logFormatText), slog.LevelDebug)
+
+	_, _ = w.Write([]byte("[download]  10.0%\r[download]  50.0%\n\n[download] Dest"))
+	_, _ = w.Write([]byte("ination: video.mp4"))
+	_ = w.Flush()
+
+	expected := "[download]  10.0%\n[download]  50.0%\n[download] Destination: video.mp4\n"
+	if out.String() != expected {
+		t.Errorf("Unexpected output:\n%q\nwant\n%q", out.String(), expected)
+	}
+}
@@ -0,0 +1,310 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/chromedp/cdproto/network"
+)
+
+const (
+	loomBaseURL     = "https://www.loom.com"
+	progressStep    = 5.0
+	httpTimeout     = 30 * time.Second
+	nativeUserAgent = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/91.0.4472.124 Safari/537.36"
+)
+
+// errUnsupportedStream is returned for Loom streams the native downloader
+// cannot handle, such as encrypted HLS
+var errUnsupportedStream = errors.New("unsupported stream format")
+
+// httpStatusError is returned when a request is answered with a non-2xx status
+type httpStatusError struct {
+	URL        string
+	StatusCode int
+}
+
+func (e *httpStatusError) Error() string {
+	return fmt.Sprintf("%s returned HTTP %d", e.URL, e.StatusCode)
+}
+
+// mediaFetcher fetches media URLs such as video files, playlists and segments
+type mediaFetcher interface {
+	get(ctx context.Context, mediaURL string) (*http.Response, error)
+}
+
+// loomClient resolves Loom share IDs to downloadable streams using the same
+// public endpoints the Loom web player uses
+type loomClient struct {
+	baseURL      string
+	httpClient   *http.Client
+	cookieHeader string
+}
+
+// newLoomClient returns a client for loom.com. Cookies for loom.com from the
+// given list are sent along, which gives access to videos that require login.
+func newLoomClient(cookies []*network.CookieParam) *loomClient {
+	return &loomClient{
+		baseURL:      loomBaseURL,
+		httpClient:   newMediaHTTPClient(),
+		cookieHeader: loomCookieHeader(cookies),
+	}
+}
+
+// newMediaHTTPClient returns an HTTP client for media downloads. It only
+// bounds the wait for response headers, since video bodies can take far
+// longer than any fixed timeout to download.
+func newMediaHTTPClient() *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.ResponseHeaderTimeout = httpTimeout
+	return &http.Client{Transport: transport}
+}
+
+// loomCookieHeader builds a Cookie header from the cookies set for loom.com
+func loomCookieHeader(cookies []*network.CookieParam) string {
+	var parts []string
+	for _, c := range cookies {
+		domain := strings.TrimPrefix(c.Domain, ".")
+		if domain == "loom.com" || strings.HasSuffix(domain, ".loom.com") {
+			parts = append(parts, c.Name+"="+c.Value)
+		}
+	}
+	return strings.Join(parts, "; ")
+}
+
+// Title returns the title of a Loom video from its oEmbed metadata
+func (c *loomClient) Title(ctx context.Context, id string) (string, error) {
+	query := url.Values{"url": {loomShareURL(id)}}
+	var response struct {
+		Title string `json:"title"`
+	}
+	if err := c.doJSON(ctx, http.MethodGet, "/v1/oembed?"+query.Encode(), nil, &response); err != nil {
+		return "", err
+	}
+	return response.Title, nil
+}
+
+// StreamURL returns the URL of the transcoded video, falling back to the
+// original upload when no transcoded version is available. The URL points to
+// an MP4 file or an HLS playlist.
+func (c *loomClient) StreamURL(ctx context.Context, id string) (string, error) {
+	var lastErr error
+	for _, kind := range []string{"transcoded-url", "raw-url"} {
+		body := map[string]any{"anonID": newAnonID(), "deviceID": nil, "force_original": false, "password": nil}
+		var response struct {
+			URL string `json:"url"`
+		}
+		err := c.doJSON(ctx, http.MethodPost, "/api/campaigns/sessions/"+id+"/"+kind, body, &response)
+		if err == nil && response.URL != "" {
+			return response.URL, nil
+		}
+		if err == nil {
+			err = fmt.Errorf("loom returned no %s for video %s", kind, id)
+		}
+		lastErr = err
+	}
+	return "", lastErr
+}
+
+// newAnonID returns a random UUID identifying an anonymous viewer
+func newAnonID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
+
+func (c *loomClient) doJSON(ctx context.Context, method, endpoint string, body, result any) error {
+	var reader io.Reader
+	if body != nil {
+		payload, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(payload)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+endpoint, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.cookieHeader != "" {
+		req.Header.Set("Cookie", c.cookieHeader)
+	}
+
+	resp, err := c.do(req)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	if err := json.NewDecoder(resp.Body).Decode(result); err != nil {
+		return fmt.Errorf("error parsing response from %s: %v", req.URL, err)
+	}
+	return nil
+}
+
+// get fetches a media URL such as a video file, playlist or segment
+func (c *loomClient) get(ctx context.Context, mediaURL string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, mediaURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	return c.do(req)
+}
+
+func (c *loomClient) do(req *http.Request) (*http.Response, error) {
+	return sendRequest(c.httpClient, req)
+}
+
+// sendRequest sends req as a browser would and turns non-2xx responses into
+// an httpStatusError
+func sendRequest(client *http.Client, req *http.Request) (*http.Response, error) {
+	req.Header.Set("User-Agent", nativeUserAgent)
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		_ = resp.Body.Close()
+		return nil, &httpStatusError{URL: req.URL.Redacted(), StatusCode: resp.StatusCode}
+	}
+	return resp, nil
+}
+
+// downloadNative downloads a Loom video without yt-dlp and returns the path
+// of the saved file
+func downloadNative(ctx context.Context, client *loomClient, video VideoRef, outputDir string, out io.Writer) (string, error) {
+	title, err := client.Title(ctx, video.VideoID)
+	if err != nil || title == "" {
+		_, _ = fmt.Fprintf(out, "⚠️ Couldn't read video title, using its ID: %v\n", err)
+		title = video.VideoID
+	}
+
+	streamURL, err := client.StreamURL(ctx, video.VideoID)
+	if err != nil {
+		return "", fmt.Errorf("couldn't resolve video stream: %w", err)
+	}
+
+	u, err := url.Parse(streamURL)
+	if err != nil {
+		return "", fmt.Errorf("invalid stream URL: %v", err)
+	}
+
+	switch strings.ToLower(path.Ext(u.Path)) {
+	case ".m3u8":
+		_, _ = fmt.Fprintf(out, "[native] Downloading HLS stream: %s\n", title)
+		return downloadHLS(ctx, client, streamURL, func(ext string) string {
+			return nativeOutputPath(outputDir, video, title, ext)
+		}, out)
+	case ".mpd":
+		_, _ = fmt.Fprintf(out, "[native] Downloading DASH stream: %s\n", title)
+		return downloadDASH(ctx, client, streamURL, func(ext string) string {
+			return nativeOutputPath(outputDir, video, title, ext)
+		}, out)
+	default:
+		ext := strings.TrimPrefix(strings.ToLower(path.Ext(u.Path)), ".")
+		if ext == "" {
+			ext = "mp4"
+		}
+		target := nativeOutputPath(outputDir, video, title, ext)
+		_, _ = fmt.Fprintf(out, "[native] Downloading %s\n", target)
+		if err := downloadFile(ctx, client, streamURL, target, out); err != nil {
+			return "", err
+		}
+		return target, nil
+	}
+}
+
+// downloadFile streams mediaURL to target through a temporary .part file so
+// an interrupted download never leaves a truncated video behind
+func downloadFile(ctx context.Context, client mediaFetcher, mediaURL, target string, out io.Writer) error {
+	resp, err := client.get(ctx, mediaURL)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+
+	partFile := target + ".part"
+	f, err := os.Create(partFile)
+	if err != nil {
+		return err
+	}
+
+	progress := &progressWriter{out: out, total: resp.ContentLength}
+	_, err = io.Copy(f, io.TeeReader(resp.Body, progress))
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(partFile)
+		return err
+	}
+	progress.Done()
+
+	return os.Rename(partFile, target)
+}
+
+// progressWriter counts the bytes passing through it and prints a progress
+// line every progressStep percent
+type progressWriter struct {
+	out      io.Writer
+	total    int64
+	written  int64
+	reported float64
+}
+
+func (p *progressWriter) Write(b []byte) (int, error) {
+	p.written += int64(len(b))
+	if p.total > 0 {
+		percent := float64(p.written) * 100 / float64(p.total)
+		if percent-p.reported >= progressStep {
+			p.reported = percent
+			_, _ = fmt.Fprintf(p.out, "\r[native] %5.1f%% of %s", percent, formatBytes(p.total))
+		}
+	}
+	return len(b), nil
+}
+
+// Done prints the final size once the download completed
+func (p *progressWriter) Done() {
+	_, _ = fmt.Fprintf(p.out, "\r[native] 100.0%% of %s\n", formatBytes(p.written))
+}
+
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%dB", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.2f%ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,333 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/chromedp/cdproto/network"
+)
+
+// fakeLoom is an httptest stand-in for the Loom endpoints used by loomClient
+type fakeLoom struct {
+	server    *httptest.Server
+	title     string
+	streamURL func(base string) string
+	files     map[string]string
+	cookies   []string
+
+	mu       sync.Mutex
+	requests map[string]int
+}
+
+func newFakeLoom(t *testing.T) *fakeLoom {
+	f := &fakeLoom{title: "My Video", files: make(map[string]string), requests: make(map[string]int)}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/oembed", func(w http.ResponseWriter, r *http.Request) {
+		f.cookies = append(f.cookies, r.Header.Get("Cookie"))
+		_ = json.NewEncoder(w).Encode(map[string]string{"title": f.title})
+	})
+	mux.HandleFunc("/api/campaigns/sessions/", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || f.streamURL == nil {
+			http.NotFound(w, r)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]string{"url": f.streamURL(f.server.URL)})
+	})
+	mux.HandleFunc("/media/", func(w http.ResponseWriter, r *http.Request) {
+		name := strings.TrimPrefix(r.URL.Path, "/media/")
+		f.mu.Lock()
+		f.requests[name]++
+		content, ok := f.files[name]
+		f.mu.Unlock()
+		if !ok || r.URL.Query().Get("sig") != "abc" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = io.WriteString(w, content)
+	})
+	f.server = httptest.NewServer(mux)
+	t.Cleanup(f.server.Close)
+	return f
+}
+
+func (f *fakeLoom) setFile(name, content string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.files[name] = content
+}
+
+func (f *fakeLoom) requestCount(name string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.requests[name]
+}
+
+func (f *fakeLoom) client(cookies []*network.CookieParam) *loomClient {
+	client := newLoomClient(cookies)
+	client.baseURL = f.server.URL
+	return client
+}
+
+func TestDownloadNative_MP4(t *testing.T) {
+	loom := newFakeLoom(t)
+	loom.files["video.mp4"] = "mp4 data"
+	loom.streamURL = func(base string) string { return base + "/media/video.mp4?sig=abc" }
+
+	outputDir := t.TempDir()
+	video := VideoRef{VideoID: "abc123", Community: "school", Course: "Course", LessonTitle: "Intro", LessonIndex: 1}
+	cookies := []*network.CookieParam{
+		{Domain: "loom.com", Name: "connect.sid", Value: "s1"},
+		{Domain: "www.skool.com", Name: "auth_token", Value: "secret"},
+	}
+
+	path, err := downloadNative(context.Background(), loom.client(cookies), video, outputDir, io.Discard)
+	if err != nil {
+		t.Fatalf("downloadNative() error = %v", err)
+	}
+
+	expected := filepath.Join(outputDir, "school", "Course", "01 - Intro - My Video [abc123].mp4")
+	if path != expected {
+		t.Errorf("downloadNative() path = %q, want %q", path, expected)
+	}
+	assertFileContent(t, path, "mp4 data")
+
+	if len(loom.cookies) == 0 || loom.cookies[0] != "connect.sid=s1" {
+		t.Errorf("Expected only Loom cookies to be sent, got %v", loom.cookies)
+	}
+}
+
+func TestDownloadNative_HLS(t *testing.T) {
+	t.Setenv("PATH", t.TempDir()) // no ffmpeg, keep the MPEG-TS stream
+	loom := newFakeLoom(t)
+	loom.files["master.m3u8"] = "#EXTM3U\n" +
+		"#EXT-X-STREAM-INF:BANDWIDTH=500000,RESOLUTION=640x360\nlow.m3u8\n" +
+		"#EXT-X-STREAM-INF:BANDWIDTH=2000000,RESOLUTION=1920x1080\nhigh.m3u8\n"
+	loom.files["high.m3u8"] = "#EXTM3U\n#EXT-X-TARGETDURATION:4\n" +
+		"#EXTINF:4.0,\nseg0.ts\n#EXTINF:4.0,\nseg1.ts\n#EXT-X-ENDLIST\n"
+	loom.files["seg0.ts"] = "first-"
+	loom.files["seg1.ts"] = "second"
+	loom.streamURL = func(base string) string { return base + "/media/master.m3u8?sig=abc" }
+
+	outputDir := t.TempDir()
+	path, err := downloadNative(context.Background(), loom.client(nil), VideoRef{VideoID: "abc123"}, outputDir, io.Discard)
+	if err != nil {
+		t.Fatalf("downloadNative() error = %v", err)
+	}
+
+	if filepath.Ext(path) != ".ts" {
+		t.Errorf("Expected MPEG-TS output, got %q", path)
+	}
+	assertFileContent(t, path, "first-second")
+}
+
+func TestDownloadNative_HLSResume(t *testing.T) {
+	t.Setenv("PATH", t.TempDir())
+	loom := newFakeLoom(t)
+	loom.files["index.m3u8"] = "#EXTM3U\n#EXTINF:4.0,\nseg0.ts\n#EXTINF:4.0,\nseg1.ts\n#EXTINF:4.0,\nseg2.ts\n#EXT-X-ENDLIST\n"
+	loom.files["seg0.ts"] = "zero-"
+	loom.files["seg1.ts"] = "one-"
+	loom.streamURL = func(base string) string { return base + "/media/index.m3u8?sig=abc" }
+
+	outputDir := t.TempDir()
+	video := VideoRef{VideoID: "abc123"}
+	if _, err := downloadNative(context.Background(), loom.client(nil), video, outputDir, io.Discard); err == nil {
+		t.Fatal("Expected error for missing segment, got nil")
+	}
+
+	target := filepath.Join(outputDir, "My Video [abc123].ts")
+	if _, err := os.Stat(target + segmentStateSuffix); err != nil {
+		t.Fatalf("Expected state file after failed download: %v", err)
+	}
+
+	loom.setFile("seg2.ts", "two")
+	path, err := downloadNative(context.Background(), loom.client(nil), video, outputDir, io.Discard)
+	if err != nil {
+		t.Fatalf("downloadNative() error = %v", err)
+	}
+	if path != target {
+		t.Errorf("downloadNative() path = %q, want %q", path, target)
+	}
+	assertFileContent(t, path, "zero-one-two")
+
+	for _, segment := range []string{"seg0.ts", "seg1.ts"} {
+		if n := loom.requestCount(segment); n != 1 {
+			t.Errorf("Expected %s to be fetched once, got %d", segment, n)
+		}
+	}
+	for _, leftover := range []string{target + ".part", target + segmentStateSuffix} {
+		if _, err := os.Stat(leftover); !os.IsNotExist(err) {
+			t.Errorf("Expected %s to be removed, got %v", leftover, err)
+		}
+	}
+}
+
+func TestDownloadNative_DASH(t *testing.T) {
+	loom := newFakeLoom(t)
+	loom.files["manifest.mpd"] = `<?xml version="1.0"?>
+<MPD xmlns="urn:mpeg:dash:schema:mpd:2011" mediaPresentationDuration="PT8S">
+  <Period>
+    <AdaptationSet mimeType="video/mp4">
+      <SegmentTemplate initialization="$RepresentationID$/init.mp4" media="$RepresentationID$/$Number$.m4s" startNumber="1" timescale="1000" duration="4000"/>
+      <Representation id="hd" bandwidth="2000000"/>
+    </AdaptationSet>
+  </Period>
+</MPD>`
+	loom.files["hd/init.mp4"] = "init-"
+	loom.files["hd/1.m4s"] = "one-"
+	loom.files["hd/2.m4s"] = "two"
+	loom.streamURL = func(base string) string { return base + "/media/manifest.mpd?sig=abc" }
+
+	outputDir := t.TempDir()
+	path, err := downloadNative(context.Background(), loom.client(nil), VideoRef{VideoID: "abc123"}, outputDir, io.Discard)
+	if err != nil {
+		t.Fatalf("downloadNative() error = %v", err)
+	}
+
+	if filepath.Ext(path) != ".mp4" {
+		t.Errorf("Expected MP4 output, got %q", path)
+	}
+	assertFileContent(t, path, "init-one-two")
+}
+
+func TestDownloadNative_DASHSeparateAudioWithoutFFmpeg(t *testing.T) {
+	t.Setenv("PATH", t.TempDir())
+	loom := newFakeLoom(t)
+	loom.files["manifest.mpd"] = `<MPD mediaPresentationDuration="PT4S"><Period>
+<AdaptationSet contentType="video"><Representation id="v" bandwidth="1"><BaseURL>v.mp4</BaseURL></Representation></AdaptationSet>
+<AdaptationSet contentType="audio"><Representation id="a" bandwidth="1"><BaseURL>a.mp4</BaseURL></Representation></AdaptationSet>
+</Period></MPD>`
+	loom.streamURL = func(base string) string { return base + "/media/manifest.mpd?sig=abc" }
+
+	_, err := downloadNative(context.Background(), loom.client(nil), VideoRef{VideoID: "abc123"}, t.TempDir(), io.Discard)
+	if !errors.Is(err, errUnsupportedStream) {
+		t.Errorf("Expected errUnsupportedStream, got %v", err)
+	}
+}
+
+// serveHLSWithAudio sets up a master playlist whose video and audio are
+// separate renditions
+func serveHLSWithAudio(loom *fakeLoom) {
+	loom.files["master.m3u8"] = "#EXTM3U\n" +
+		"#EXT-X-MEDIA:TYPE=AUDIO,GROUP-ID=\"aud\",NAME=\"English\",DEFAULT=YES,URI=\"audio.m3u8\"\n" +
+		"#EXT-X-STREAM-INF:BANDWIDTH=2000000,AUDIO=\"aud\"\nvideo.m3u8\n"
+	loom.files["video.m3u8"] = "#EXTM3U\n#EXTINF:4.0,\nv0.ts\n#EXT-X-ENDLIST\n"
+	loom.files["audio.m3u8"] = "#EXTM3U\n#EXTINF:4.0,\na0.ts\n#EXT-X-ENDLIST\n"
+	loom.files["v0.ts"] = "video-"
+	loom.files["a0.ts"] = "audio"
+	loom.streamURL = func(base string) string { return base + "/media/master.m3u8?sig=abc" }
+}
+
+func TestDownloadNative_HLSSeparateAudio(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("fake ffmpeg is a shell script")
+	}
+	// The fake ffmpeg joins its inputs, which shows that both tracks are muxed
+	bin := t.TempDir()
+	script := "#!/bin/sh\ntmp=\"$0.out\"\n: > \"$tmp\"\nwhile [ $# -gt 0 ]; do\n" +
+		"  if [ \"$1\" = \"-i\" ]; then cat \"$2\" >> \"$tmp\"; shift; fi\n" +
+		"  out=\"$1\"; shift\ndone\nmv \"$tmp\" \"$out\"\n"
+	if err := os.WriteFile(filepath.Join(bin, "ffmpeg"), []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+	loom := newFakeLoom(t)
+	serveHLSWithAudio(loom)
+
+	outputDir := t.TempDir()
+	path, err := downloadNative(context.Background(), loom.client(nil), VideoRef{VideoID: "abc123"}, outputDir, io.Discard)
+	if err != nil {
+		t.Fatalf("downloadNative() error = %v", err)
+	}
+
+	if expected := filepath.Join(outputDir, "My Video [abc123].mp4"); path != expected {
+		t.Errorf("downloadNative() path = %q, want %q", path, expected)
+	}
+	assertFileContent(t, path, "video-audio")
+	if entries, _ := os.ReadDir(outputDir); len(entries) != 1 {
+		t.Errorf("Expected only the muxed file to be left, got %d files", len(entries))
+	}
+}
+
+func TestDownloadNative_HLSSeparateAudioWithoutFFmpeg(t *testing.T) {
+	t.Setenv("PATH", t.TempDir())
+	loom := newFakeLoom(t)
+	serveHLSWithAudio(loom)
+
+	_, err := downloadNative(context.Background(), loom.client(nil), VideoRef{VideoID: "abc123"}, t.TempDir(), io.Discard)
+	if !errors.Is(err, errUnsupportedStream) {
+		t.Errorf("Expected errUnsupportedStream, got %v", err)
+	}
+}
+
+func TestDownloadNative_NotFound(t *testing.T) {
+	loom := newFakeLoom(t)
+
+	_, err := downloadNative(context.Background(), loom.client(nil), VideoRef{VideoID: "missing"}, t.TempDir(), io.Discard)
+	var statusErr *httpStatusError
+	if !errors.As(err, &statusErr) {
+		t.Fatalf("Expected httpStatusError, got %v", err)
+	}
+	if statusErr.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected HTTP 404, got %d", statusErr.StatusCode)
+	}
+}
+
+func TestDownloadNative_MissingMedia(t *testing.T) {
+	loom := newFakeLoom(t)
+	loom.streamURL = func(base string) string { return base + "/media/missing.mp4?sig=abc" }
+
+	outputDir := t.TempDir()
+	if _, err := downloadNative(context.Background(), loom.client(nil), VideoRef{VideoID: "abc123"}, outputDir, io.Discard); err == nil {
+		t.Fatal("Expected error for missing media, got nil")
+	}
+
+	entries, err := os.ReadDir(outputDir)
+	if err != nil {
+		t.Fatalf("Failed to read output directory: %v", err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("Expected no files after failed download, got %d", len(entries))
+	}
+}
+
+func TestFormatBytes(t *testing.T) {
+	tests := []struct {
+		input    int64
+		expected string
+	}{
+		{512, "512B"},
+		{1536, "1.50KiB"},
+		{5 * 1024 * 1024, "5.00MiB"},
+		{3 * 1024 * 1024 * 1024, "3.00GiB"},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprint(tt.input), func(t *testing.T) {
+			if result := formatBytes(tt.input); result != tt.expected {
+				t.Errorf("formatBytes(%d) = %q, want %q", tt.input, result, tt.expected)
+			}
+		})
+	}
+}
+
+func assertFileContent(t *testing.T, path, expected string) {
+	t.Helper()
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read %s: %v", path, err)
+	}
+	if string(content) != expected {
+		t.Errorf("Content of %s = %q, want %q", path, content, expected)
+	}
+}
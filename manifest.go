@@ -0,0 +1,243 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Formats supported by -export
+const (
+	exportFormatText = "txt"
+	exportFormatJSON = "json"
+	exportFormatCSV  = "csv"
+)
+
+// csvHeader lists the columns written by the csv export format
+var csvHeader = []string{
+	"provider", "video_id", "url", "original_url", "form", "source_url", "index",
+	"community", "course", "module", "module_index", "lesson_title", "lesson_index",
+}
+
+// videoManifest is the document written by the json export format
+type videoManifest struct {
+	SourceURL string     `json:"source_url"`
+	Videos    []VideoRef `json:"videos"`
+}
+
+func isExportFormat(format string) bool {
+	switch format {
+	case exportFormatText, exportFormatJSON, exportFormatCSV:
+		return true
+	}
+	return false
+}
+
+// exportVideos writes the video list to config.ExportFile, or to stdout when
+// it is "-"
+func exportVideos(videos []VideoRef, config Config, stdout io.Writer) error {
+	if config.ExportFile == "-" {
+		return writeVideos(stdout, config.Export, config.SkoolURL, videos)
+	}
+
+	f, err := os.Create(config.ExportFile)
+	if err != nil {
+		return err
+	}
+	if err := writeVideos(f, config.Export, config.SkoolURL, videos); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// writeVideos writes the videos in the given export format
+func writeVideos(w io.Writer, format, sourceURL string, videos []VideoRef) error {
+	switch format {
+	case exportFormatText:
+		return writeVideosText(w, videos)
+	case exportFormatJSON:
+		return writeVideosJSON(w, sourceURL, videos)
+	case exportFormatCSV:
+		return writeVideosCSV(w, videos)
+	}
+	return fmt.Errorf("unknown export format %q", format)
+}
+
+// writeVideosText writes one share URL per line. Videos are grouped by lesson
+// under a comment naming the lesson, so the list stays readable while tools
+// that skip # lines can consume it directly.
+func writeVideosText(w io.Writer, videos []VideoRef) error {
+	lastLesson := ""
+	for _, video := range videos {
+		if lesson := videoLessonLabel(video); lesson != "" && lesson != lastLesson {
+			if _, err := fmt.Fprintf(w, "# %s\n", lesson); err != nil {
+				return err
+			}
+			lastLesson = lesson
+		}
+		if _, err := fmt.Fprintln(w, video.URL); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeVideosJSON(w io.Writer, sourceURL string, videos []VideoRef) error {
+	if videos == nil {
+		videos = []VideoRef{}
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(videoManifest{SourceURL: sourceURL, Videos: videos})
+}
+
+func writeVideosCSV(w io.Writer, videos []VideoRef) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(csvHeader); err != nil {
+		return err
+	}
+
+	for _, v := range videos {
+		record := []string{
+			v.Provider, v.VideoID, v.URL, v.OriginalURL, v.Form, v.SourceURL, strconv.Itoa(v.Index),
+			v.Community, v.Course, v.Module, strconv.Itoa(v.ModuleIndex), v.LessonTitle, strconv.Itoa(v.LessonIndex),
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// videoLessonLabel describes where in a course a video was found, e.g.
+// "Course › Module › Lesson"
+func videoLessonLabel(video VideoRef) string {
+	var parts []string
+	for _, part := range []string{video.Course, video.Module, video.LessonTitle} {
+		if part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return strings.Join(parts, " › ")
+}
+
+// loadVideos reads a video list written by -export from path, or from stdin
+// when path is "-"
+func loadVideos(path string) ([]VideoRef, error) {
+	if path == "-" {
+		return readVideos(os.Stdin)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+
+	return readVideos(f)
+}
+
+// readVideos parses a video list in the json export format (or a bare JSON
+// array of videos) or as plain text with one video URL per line. Blank lines
+// and # comments are ignored.
+func readVideos(r io.Reader) ([]VideoRef, error) {
+	content, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	trimmed := bytes.TrimSpace(content)
+	if bytes.HasPrefix(trimmed, []byte("{")) || bytes.HasPrefix(trimmed, []byte("[")) {
+		return readVideosJSON(trimmed)
+	}
+	return readVideosText(content)
+}
+
+func readVideosJSON(content []byte) ([]VideoRef, error) {
+	var entries []VideoRef
+	if bytes.HasPrefix(content, []byte("[")) {
+		if err := json.Unmarshal(content, &entries); err != nil {
+			return nil, fmt.Errorf("error parsing JSON video list: %v", err)
+		}
+	} else {
+		var manifest struct {
+			Videos []VideoRef `json:"videos"`
+		}
+		if err := json.Unmarshal(content, &manifest); err != nil {
+			return nil, fmt.Errorf("error parsing JSON video list: %v", err)
+		}
+		entries = manifest.Videos
+	}
+
+	videos := make([]VideoRef, 0, len(entries))
+	for i, video := range entries {
+		if video.VideoID != "" {
+			provider, ok := providerByName(video.Provider)
+			if !ok {
+				return nil, fmt.Errorf("video %d: unknown provider %q", i+1, video.Provider)
+			}
+			if video.URL == "" {
+				// Playlist URLs cannot be rebuilt from an ID
+				if provider.Canonical == nil {
+					return nil, fmt.Errorf("video %d: %s video %s has no URL", i+1, provider.Label, video.VideoID)
+				}
+				video.URL = provider.Canonical(video.VideoID)
+			}
+			videos = append(videos, video)
+			continue
+		}
+
+		// Entries written by hand may only carry a URL
+		found := extractVideos(video.URL, video.SourceURL)
+		if len(found) == 0 {
+			return nil, fmt.Errorf("video %d: not a supported video URL: %q", i+1, video.URL)
+		}
+		video.Provider = found[0].Provider
+		video.VideoID = found[0].VideoID
+		video.URL = found[0].URL
+		if video.OriginalURL == "" {
+			video.OriginalURL = found[0].OriginalURL
+			video.Form = found[0].Form
+		}
+		videos = append(videos, video)
+	}
+
+	return uniqueVideos(videos), nil
+}
+
+func readVideosText(content []byte) ([]VideoRef, error) {
+	var videos []VideoRef
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		found := extractVideos(line, "")
+		if len(found) == 0 {
+			return nil, fmt.Errorf("line %d: not a supported video URL: %q", lineNum, line)
+		}
+		for _, video := range found {
+			video.Index = len(videos) + 1
+			videos = append(videos, video)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return uniqueVideos(videos), nil
+}
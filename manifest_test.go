@@ -0,0 +1,185 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func testVideos() []VideoRef {
+	return []VideoRef{
+		{
+			Provider: providerLoom, VideoID: "abc123", URL: loomShareURL("abc123"), OriginalURL: "https://loom.com/embed/abc123",
+			Form: videoFormEmbed, SourceURL: "https://www.skool.com/school/classroom/c?md=1", Index: 1,
+			Community: "school", Course: "Course", Module: "Basics", ModuleIndex: 1, LessonTitle: "Setup", LessonIndex: 1,
+		},
+		{
+			Provider: providerLoom, VideoID: "def456", URL: loomShareURL("def456"), OriginalURL: loomShareURL("def456"),
+			Form: videoFormShare, SourceURL: "https://www.skool.com/school/classroom/c?md=1", Index: 2,
+			Community: "school", Course: "Course", Module: "Basics", ModuleIndex: 1, LessonTitle: "Setup", LessonIndex: 1,
+		},
+		{
+			Provider: providerLoom, VideoID: "ghi789", URL: loomShareURL("ghi789"), OriginalURL: loomShareURL("ghi789"),
+			Form: videoFormShare, SourceURL: "https://www.skool.com/school/classroom/c?md=2", Index: 1,
+			Community: "school", Course: "Course", Module: "Basics", ModuleIndex: 1, LessonTitle: "Next, \"quoted\"", LessonIndex: 2,
+		},
+	}
+}
+
+func TestWriteVideos_Text(t *testing.T) {
+	var buf bytes.Buffer
+	if err := writeVideos(&buf, exportFormatText, "", testVideos()); err != nil {
+		t.Fatalf("writeVideos() error = %v", err)
+	}
+
+	expected := "# Course › Basics › Setup\n" +
+		"https://www.loom.com/share/abc123\n" +
+		"https://www.loom.com/share/def456\n" +
+		"# Course › Basics › Next, \"quoted\"\n" +
+		"https://www.loom.com/share/ghi789\n"
+	if buf.String() != expected {
+		t.Errorf("writeVideos() =\n%s\nwant\n%s", buf.String(), expected)
+	}
+}
+
+func TestWriteVideos_JSON(t *testing.T) {
+	var buf bytes.Buffer
+	if err := writeVideos(&buf, exportFormatJSON, "https://www.skool.com/school/classroom", testVideos()); err != nil {
+		t.Fatalf("writeVideos() error = %v", err)
+	}
+
+	var manifest videoManifest
+	if err := json.Unmarshal(buf.Bytes(), &manifest); err != nil {
+		t.Fatalf("Failed to parse JSON output: %v", err)
+	}
+	if manifest.SourceURL != "https://www.skool.com/school/classroom" {
+		t.Errorf("Expected source URL to be recorded, got %q", manifest.SourceURL)
+	}
+	if !reflect.DeepEqual(manifest.Videos, testVideos()) {
+		t.Errorf("JSON round trip = %v, want %v", manifest.Videos, testVideos())
+	}
+}
+
+func TestWriteVideos_JSONEmpty(t *testing.T) {
+	var buf bytes.Buffer
+	if err := writeVideos(&buf, exportFormatJSON, "", nil); err != nil {
+		t.Fatalf("writeVideos() error = %v", err)
+	}
+	if !strings.Contains(buf.String(), `"videos": []`) {
+		t.Errorf("Expected empty video list, got %s", buf.String())
+	}
+}
+
+func TestWriteVideos_CSV(t *testing.T) {
+	var buf bytes.Buffer
+	if err := writeVideos(&buf, exportFormatCSV, "", testVideos()); err != nil {
+		t.Fatalf("writeVideos() error = %v", err)
+	}
+
+	records, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatalf("Failed to parse CSV output: %v", err)
+	}
+	if len(records) != 4 {
+		t.Fatalf("Expected header and 3 rows, got %d records", len(records))
+	}
+	if !reflect.DeepEqual(records[0], csvHeader) {
+		t.Errorf("Unexpected header %v", records[0])
+	}
+	if records[1][0] != providerLoom || records[1][1] != "abc123" || records[1][4] != videoFormEmbed {
+		t.Errorf("Unexpected first row %v", records[1])
+	}
+	if records[3][11] != "Next, \"quoted\"" || records[3][12] != "2" {
+		t.Errorf("Unexpected last row %v", records[3])
+	}
+}
+
+func TestWriteVideos_UnknownFormat(t *testing.T) {
+	if err := writeVideos(&bytes.Buffer{}, "xml", "", testVideos()); err == nil {
+		t.Error("Expected error for unknown format, got nil")
+	}
+}
+
+func TestReadVideos_JSONRoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+	if err := writeVideos(&buf, exportFormatJSON, "https://www.skool.com/school/classroom", testVideos()); err != nil {
+		t.Fatalf("writeVideos() error = %v", err)
+	}
+
+	videos, err := readVideos(&buf)
+	if err != nil {
+		t.Fatalf("readVideos() error = %v", err)
+	}
+	if !reflect.DeepEqual(videos, testVideos()) {
+		t.Errorf("readVideos() = %v, want %v", videos, testVideos())
+	}
+}
+
+func TestReadVideos_JSONArray(t *testing.T) {
+	content := `[
+		{"url": "https://loom.com/embed/abc123", "lesson_title": "Setup"},
+		{"provider": "loom", "video_id": "def456"},
+		{"url": "https://www.loom.com/share/abc123"}
+	]`
+
+	videos, err := readVideos(strings.NewReader(content))
+	if err != nil {
+		t.Fatalf("readVideos() error = %v", err)
+	}
+
+	expected := []VideoRef{
+		{Provider: providerLoom, VideoID: "abc123", URL: loomShareURL("abc123"), OriginalURL: "https://loom.com/embed/abc123", Form: videoFormEmbed, LessonTitle: "Setup"},
+		{Provider: providerLoom, VideoID: "def456", URL: loomShareURL("def456")},
+	}
+	if !reflect.DeepEqual(videos, expected) {
+		t.Errorf("readVideos() = %v, want %v", videos, expected)
+	}
+}
+
+func TestReadVideos_Text(t *testing.T) {
+	var buf bytes.Buffer
+	if err := writeVideos(&buf, exportFormatText, "", testVideos()); err != nil {
+		t.Fatalf("writeVideos() error = %v", err)
+	}
+	buf.WriteString("\n  https://loom.com/embed/xyz000  \n")
+
+	videos, err := readVideos(&buf)
+	if err != nil {
+		t.Fatalf("readVideos() error = %v", err)
+	}
+
+	var ids []string
+	for _, video := range videos {
+		ids = append(ids, video.VideoID)
+	}
+	expected := []string{"abc123", "def456", "ghi789", "xyz000"}
+	if !reflect.DeepEqual(ids, expected) {
+		t.Errorf("readVideos() IDs = %v, want %v", ids, expected)
+	}
+	if videos[3].Index != 4 || videos[3].Form != videoFormEmbed {
+		t.Errorf("Unexpected last video %v", videos[3])
+	}
+}
+
+func TestReadVideos_Invalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{"Non-Loom line", "https://www.loom.com/share/abc123\nhttps://example.com/video\n"},
+		{"Broken JSON", `{"videos": [`},
+		{"JSON entry without Loom URL", `[{"url": "https://example.com/video"}]`},
+		{"Skool video without URL", `[{"provider": "skool", "video_id": "b5055b62105c"}]`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := readVideos(strings.NewReader(tt.content)); err == nil {
+				t.Error("Expected error, got nil")
+			}
+		})
+	}
+}
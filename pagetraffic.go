@@ -0,0 +1,154 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/chromedp"
+)
+
+const (
+	// maxResponseBodies and maxResponseSize limit how much of a page's API
+	// traffic is read back from the browser
+	maxResponseBodies = 50
+	maxResponseSize   = 5 << 20
+)
+
+// loomSessionRegex matches the Loom URLs other than share and embed links
+// that name a video: API calls, CDN thumbnails and media, and streams
+var loomSessionRegex = regexp.MustCompile(`loom\.com/(?:api/campaigns/sessions|sessions/(?:thumbnails|transcoded|raw|gifs))/([a-zA-Z0-9]+)|luna\.loom\.com/id/([a-zA-Z0-9]+)`)
+
+// pageTraffic records the requests of a page that may name a video: any
+// request to a supported video host or for a playlist of a video uploaded to
+// Skool, and the JSON responses of the Skool and Loom APIs. Players loaded by script often never put a video link in the
+// page itself.
+type pageTraffic struct {
+	mu        sync.Mutex
+	urls      []string
+	pending   map[network.RequestID]bool // JSON responses still loading
+	responses []network.RequestID
+}
+
+// watchPageTraffic records the traffic of the page in ctx until the returned
+// stop function is called
+func watchPageTraffic(ctx context.Context) (*pageTraffic, func()) {
+	traffic := &pageTraffic{pending: make(map[network.RequestID]bool)}
+	listenCtx, cancel := context.WithCancel(ctx)
+	chromedp.ListenTarget(listenCtx, traffic.handle)
+	return traffic, cancel
+}
+
+// handle records a browser event. It is called from the browser's event
+// loop, so response bodies are only fetched later by texts.
+func (t *pageTraffic) handle(ev any) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	switch ev := ev.(type) {
+	case *network.EventRequestWillBeSent:
+		if isProviderURL(ev.Request.URL) || isSkoolPlaylistURL(ev.Request.URL) {
+			t.urls = append(t.urls, ev.Request.URL)
+		}
+	case *network.EventResponseReceived:
+		if isVideoAPIResponse(ev.Response) {
+			t.pending[ev.RequestID] = true
+		}
+	case *network.EventLoadingFinished:
+		if t.pending[ev.RequestID] {
+			delete(t.pending, ev.RequestID)
+			if ev.EncodedDataLength <= maxResponseSize && len(t.responses) < maxResponseBodies {
+				t.responses = append(t.responses, ev.RequestID)
+			}
+		}
+	case *network.EventLoadingFailed:
+		delete(t.pending, ev.RequestID)
+	}
+}
+
+// texts returns the recorded request URLs and response bodies, followed by
+// the share URLs of the Loom videos they name
+func (t *pageTraffic) texts(ctx context.Context) []string {
+	t.mu.Lock()
+	texts := append([]string(nil), t.urls...)
+	responses := append([]network.RequestID(nil), t.responses...)
+	t.mu.Unlock()
+
+	for i, raw := range texts {
+		// Loom links are often passed on as query parameters. Playlist URLs
+		// are kept as requested since their signature must stay intact.
+		if isHLSPlaylistURL(raw) {
+			continue
+		}
+		if unescaped, err := url.QueryUnescape(raw); err == nil {
+			texts[i] = unescaped
+		}
+	}
+
+	for _, id := range responses {
+		var body []byte
+		err := runWithTimeout(ctx, chromedp.ActionFunc(func(ctx context.Context) error {
+			var err error
+			body, err = network.GetResponseBody(id).Do(ctx)
+			return err
+		}))
+		if err != nil {
+			// The browser drops bodies it no longer needs
+			slog.Debug("⚠️ Could not read response body", "error", err)
+			continue
+		}
+		texts = append(texts, string(body))
+		texts = append(texts, jsonStrings(string(body))...)
+	}
+
+	var ids []string
+	for _, text := range texts {
+		ids = append(ids, loomSessionIDs(text)...)
+	}
+	for _, id := range ids {
+		texts = append(texts, loomShareURL(id))
+	}
+	return texts
+}
+
+// loomSessionIDs returns the IDs of the Loom videos named by API, CDN and
+// stream URLs in text
+func loomSessionIDs(text string) []string {
+	var ids []string
+	for _, match := range loomSessionRegex.FindAllStringSubmatch(text, -1) {
+		if match[1] != "" {
+			ids = append(ids, match[1])
+		} else {
+			ids = append(ids, match[2])
+		}
+	}
+	return ids
+}
+
+// isLoomURL reports whether raw points at loom.com or one of its subdomains
+func isLoomURL(raw string) bool {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return false
+	}
+	host := u.Hostname()
+	return host == "loom.com" || strings.HasSuffix(host, ".loom.com")
+}
+
+// isVideoAPIResponse reports whether a response is JSON from Skool or Loom,
+// which may describe the videos of a lesson
+func isVideoAPIResponse(response *network.Response) bool {
+	if response == nil || !strings.Contains(response.MimeType, "json") {
+		return false
+	}
+	u, err := url.Parse(response.URL)
+	if err != nil {
+		return false
+	}
+	host := u.Hostname()
+	return isLoomURL(response.URL) || host == "skool.com" || strings.HasSuffix(host, ".skool.com")
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/chromedp/cdproto/network"
+)
+
+func TestLoomSessionIDs(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		expected []string
+	}{
+		{name: "stream URL API", text: "https://www.loom.com/api/campaigns/sessions/abc123/transcoded-url", expected: []string{"abc123"}},
+		{name: "thumbnail", text: "https://cdn.loom.com/sessions/thumbnails/def456-with-play.gif", expected: []string{"def456"}},
+		{name: "transcoded media", text: "https://cdn.loom.com/sessions/transcoded/ghi789.mp4?Policy=x", expected: []string{"ghi789"}},
+		{name: "HLS stream", text: "https://luna.loom.com/id/jkl012/rev/1/resource/hls/playlist.m3u8", expected: []string{"jkl012"}},
+		{name: "API response", text: `{"url":"https://luna.loom.com/id/abc123/playlist.m3u8","thumb":"https://cdn.loom.com/sessions/thumbnails/def456.jpg"}`, expected: []string{"abc123", "def456"}},
+		{name: "unrelated", text: "https://www.loom.com/api/user/settings", expected: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := loomSessionIDs(tt.text); !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("loomSessionIDs() = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestIsVideoAPIResponse(t *testing.T) {
+	tests := []struct {
+		name     string
+		response *network.Response
+		expected bool
+	}{
+		{name: "Skool API", response: &network.Response{URL: "https://api2.skool.com/courses/abc", MimeType: "application/json"}, expected: true},
+		{name: "Skool page data", response: &network.Response{URL: "https://www.skool.com/_next/data/x/school/classroom.json", MimeType: "application/json"}, expected: true},
+		{name: "Loom API", response: &network.Response{URL: "https://www.loom.com/v1/oembed", MimeType: "application/json; charset=utf-8"}, expected: true},
+		{name: "Skool page", response: &network.Response{URL: "https://www.skool.com/school/classroom", MimeType: "text/html"}, expected: false},
+		{name: "other site", response: &network.Response{URL: "https://api.notskool.com/data", MimeType: "application/json"}, expected: false},
+		{name: "no response", response: nil, expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isVideoAPIResponse(tt.response); got != tt.expected {
+				t.Errorf("isVideoAPIResponse() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestPageTraffic(t *testing.T) {
+	traffic := &pageTraffic{pending: make(map[network.RequestID]bool)}
+	for _, u := range []string{
+		"https://www.skool.com/api/lesson",
+		"https://www.loom.com/v1/oembed?url=https%3A%2F%2Fwww.loom.com%2Fshare%2Fabc123",
+		"https://cdn.loom.com/sessions/thumbnails/def456-with-play.gif",
+		"https://notloom.com/sessions/thumbnails/ghi789.gif",
+		"https://luna.loom.com/id/def456/rev/1/resource/hls/playlist.m3u8",
+		"https://stream.video.skool.com/jkl012/master.m3u8?token=a%2Bb",
+		"https://manifest.googlevideo.com/api/manifest/hls_variant/file/index.m3u8",
+	} {
+		traffic.handle(&network.EventRequestWillBeSent{Request: &network.Request{URL: u}})
+	}
+
+	// JSON responses are kept for reading once they have finished loading
+	traffic.handle(&network.EventResponseReceived{RequestID: "api", Response: &network.Response{URL: "https://api2.skool.com/lesson", MimeType: "application/json"}})
+	traffic.handle(&network.EventResponseReceived{RequestID: "failed", Response: &network.Response{URL: "https://api2.skool.com/other", MimeType: "application/json"}})
+	traffic.handle(&network.EventResponseReceived{RequestID: "page", Response: &network.Response{URL: "https://www.skool.com/", MimeType: "text/html"}})
+	traffic.handle(&network.EventLoadingFailed{RequestID: "failed"})
+	traffic.handle(&network.EventLoadingFinished{RequestID: "api"})
+	traffic.handle(&network.EventLoadingFinished{RequestID: "page"})
+	if !reflect.DeepEqual(traffic.responses, []network.RequestID{"api"}) || len(traffic.pending) != 0 {
+		t.Errorf("responses = %q, pending = %v, want only the finished API response", traffic.responses, traffic.pending)
+	}
+
+	traffic.responses = nil
+	videos := extractVideos(strings.Join(traffic.texts(context.Background()), "\n"), "")
+	var ids []string
+	for _, video := range videos {
+		ids = append(ids, video.VideoID)
+	}
+	skoolID, _, _ := identifySkoolVideo("https://stream.video.skool.com/jkl012/master.m3u8")
+	if expected := []string{"abc123", skoolID, "def456"}; !reflect.DeepEqual(ids, expected) {
+		t.Errorf("videos from traffic = %q, want %q", ids, expected)
+	}
+	// The playlist's signature is passed on unchanged
+	if len(videos) == 3 && videos[1].URL != "https://stream.video.skool.com/jkl012/master.m3u8?token=a%2Bb" {
+		t.Errorf("Skool video URL = %q, want the requested playlist URL", videos[1].URL)
+	}
+}
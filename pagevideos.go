@@ -0,0 +1,189 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/dom"
+	"github.com/chromedp/cdproto/page"
+	"github.com/chromedp/cdproto/runtime"
+	"github.com/chromedp/cdproto/target"
+	"github.com/chromedp/chromedp"
+)
+
+const (
+	// lazyLoadWaitTime bounds the wait for players that load once the page
+	// is scrolled
+	lazyLoadWaitTime = 3 * time.Second
+)
+
+// scrollPageJS scrolls through the page one screen at a time, for at most 50
+// screens, so lazily loaded players are attached, then returns to the top
+const scrollPageJS = `(async () => {
+	const pause = () => new Promise(resolve => setTimeout(resolve, 100));
+	const step = Math.max(window.innerHeight, 200);
+	for (let i = 0, y = 0; i < 50 && y < document.documentElement.scrollHeight; i++, y += step) {
+		window.scrollTo(0, y);
+		await pause();
+	}
+	window.scrollTo(0, 0);
+	return true;
+})()`
+
+// nextDataJS returns the page state the Skool app embeds for its first render
+const nextDataJS = `(() => {
+	const el = document.getElementById('__NEXT_DATA__');
+	return el ? el.textContent : '';
+})()`
+
+// networkIdleJS treats the page as rendered so only the network is waited for
+const networkIdleJS = `({rendered: true, complete: false})`
+
+// pageVideos collects the videos of the loaded page. Besides the page
+// HTML it looks at the DOM of every frame and shadow root, the URLs of
+// cross-origin frames, the page state embedded as JSON and the video IDs
+// in traffic, after scrolling through the page to attach lazily loaded
+// players. Videos are numbered in that order and reported once.
+func pageVideos(ctx context.Context, sourceURL string, traffic *pageTraffic) ([]VideoRef, error) {
+	if err := runWithTimeout(ctx, runAndWaitReady(chromedp.Evaluate(scrollPageJS, nil, awaitPromise), networkIdleJS, lazyLoadWaitTime)); err != nil {
+		slog.Debug("⚠️ Could not scroll the page", "error", err)
+	}
+
+	var html string
+	if err := runWithTimeout(ctx, chromedp.OuterHTML("html", &html)); err != nil {
+		return nil, err
+	}
+	texts := []string{html}
+
+	// The other sources only add to the HTML, so a failure is not fatal
+	var root *cdp.Node
+	if err := runWithTimeout(ctx, chromedp.ActionFunc(func(ctx context.Context) error {
+		var err error
+		root, err = dom.GetDocument().WithDepth(-1).WithPierce(true).Do(ctx)
+		return err
+	})); err != nil {
+		slog.Debug("⚠️ Could not read the page DOM", "error", err)
+	} else {
+		texts = append(texts, nodeTexts(root)...)
+	}
+
+	if urls, err := frameURLs(ctx); err != nil {
+		slog.Debug("⚠️ Could not read the page frames", "error", err)
+	} else {
+		texts = append(texts, urls...)
+	}
+
+	var nextData string
+	if err := runWithTimeout(ctx, chromedp.Evaluate(nextDataJS, &nextData)); err != nil {
+		slog.Debug("⚠️ Could not read the page state", "error", err)
+	} else {
+		texts = append(texts, jsonStrings(nextData)...)
+	}
+
+	if traffic != nil {
+		texts = append(texts, traffic.texts(ctx)...)
+	}
+
+	return extractVideos(strings.Join(texts, "\n"), sourceURL), nil
+}
+
+func awaitPromise(p *runtime.EvaluateParams) *runtime.EvaluateParams {
+	return p.WithAwaitPromise(true)
+}
+
+// frameURLs returns the URLs of every frame of the page, including
+// cross-origin frames that run as separate browser targets
+func frameURLs(ctx context.Context) ([]string, error) {
+	var tree *page.FrameTree
+	var targets []*target.Info
+	if err := runWithTimeout(ctx, chromedp.ActionFunc(func(ctx context.Context) error {
+		var err error
+		if tree, err = page.GetFrameTree().Do(ctx); err != nil {
+			return err
+		}
+		targets, err = target.GetTargets().Do(ctx)
+		return err
+	})); err != nil {
+		return nil, err
+	}
+
+	urls := frameTreeURLs(tree)
+	for _, info := range targets {
+		if info.Type == "iframe" {
+			urls = append(urls, info.URL)
+		}
+	}
+	return urls, nil
+}
+
+// frameTreeURLs returns the URLs of a frame and all frames nested in it
+func frameTreeURLs(tree *page.FrameTree) []string {
+	if tree == nil {
+		return nil
+	}
+	var urls []string
+	if tree.Frame != nil {
+		urls = append(urls, tree.Frame.URL)
+	}
+	for _, child := range tree.ChildFrames {
+		urls = append(urls, frameTreeURLs(child)...)
+	}
+	return urls
+}
+
+// nodeTexts returns the attribute values and text of node and every node
+// below it, including the documents of frames and shadow roots
+func nodeTexts(node *cdp.Node) []string {
+	if node == nil {
+		return nil
+	}
+	var texts []string
+	for i := 1; i < len(node.Attributes); i += 2 {
+		texts = append(texts, node.Attributes[i])
+	}
+	if node.NodeType == cdp.NodeTypeText && strings.TrimSpace(node.NodeValue) != "" {
+		texts = append(texts, node.NodeValue)
+	}
+	for _, child := range node.Children {
+		texts = append(texts, nodeTexts(child)...)
+	}
+	for _, shadow := range node.ShadowRoots {
+		texts = append(texts, nodeTexts(shadow)...)
+	}
+	return append(texts, nodeTexts(node.ContentDocument)...)
+}
+
+// jsonStrings returns every string value in a JSON document, so URLs are
+// found however the JSON escapes them
+func jsonStrings(content string) []string {
+	var data any
+	if content == "" || json.Unmarshal([]byte(content), &data) != nil {
+		return nil
+	}
+
+	var texts []string
+	var walk func(v any)
+	walk = func(v any) {
+		switch v := v.(type) {
+		case string:
+			texts = append(texts, v)
+		case []any:
+			for _, item := range v {
+				walk(item)
+			}
+		case map[string]any:
+			// Sorted so videos are numbered the same on every run
+			for _, key := range slices.Sorted(maps.Keys(v)) {
+				walk(v[key])
+			}
+		}
+	}
+	walk(data)
+	return texts
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/page"
+)
+
+func TestNodeTexts(t *testing.T) {
+	root := &cdp.Node{
+		NodeType: cdp.NodeTypeDocument,
+		Children: []*cdp.Node{
+			{
+				NodeName:   "DIV",
+				NodeType:   cdp.NodeTypeElement,
+				Attributes: []string{"class", "player", "data-src", "https://www.loom.com/share/lazy123"},
+				Children:   []*cdp.Node{{NodeType: cdp.NodeTypeText, NodeValue: "Lesson text"}, {NodeType: cdp.NodeTypeText, NodeValue: "  "}},
+			},
+			{
+				NodeName: "IFRAME",
+				NodeType: cdp.NodeTypeElement,
+				ContentDocument: &cdp.Node{
+					NodeType: cdp.NodeTypeDocument,
+					Children: []*cdp.Node{{NodeName: "A", NodeType: cdp.NodeTypeElement, Attributes: []string{"href", "https://www.loom.com/share/frame456"}}},
+				},
+			},
+			{
+				NodeName:    "SKOOL-PLAYER",
+				NodeType:    cdp.NodeTypeElement,
+				ShadowRoots: []*cdp.Node{{NodeType: cdp.NodeTypeDocumentFragment, Children: []*cdp.Node{{NodeType: cdp.NodeTypeText, NodeValue: "https://www.loom.com/embed/shadow789"}}}},
+			},
+		},
+	}
+
+	expected := []string{"player", "https://www.loom.com/share/lazy123", "Lesson text", "https://www.loom.com/share/frame456", "https://www.loom.com/embed/shadow789"}
+	if got := nodeTexts(root); !reflect.DeepEqual(got, expected) {
+		t.Errorf("nodeTexts() = %q, want %q", got, expected)
+	}
+}
+
+func TestFrameTreeURLs(t *testing.T) {
+	tree := &page.FrameTree{
+		Frame: &cdp.Frame{URL: "https://www.skool.com/school/classroom/abc?md=1"},
+		ChildFrames: []*page.FrameTree{
+			{Frame: &cdp.Frame{URL: "https://www.loom.com/embed/abc123"}},
+			{Frame: &cdp.Frame{URL: "about:blank"}, ChildFrames: []*page.FrameTree{{Frame: &cdp.Frame{URL: "https://www.loom.com/embed/def456"}}}},
+		},
+	}
+
+	expected := []string{"https://www.skool.com/school/classroom/abc?md=1", "https://www.loom.com/embed/abc123", "about:blank", "https://www.loom.com/embed/def456"}
+	if got := frameTreeURLs(tree); !reflect.DeepEqual(got, expected) {
+		t.Errorf("frameTreeURLs() = %q, want %q", got, expected)
+	}
+	if got := frameTreeURLs(nil); got != nil {
+		t.Errorf("frameTreeURLs(nil) = %q, want nil", got)
+	}
+}
+
+func TestJSONStrings(t *testing.T) {
+	content := `{"props": {"pageProps": {"lesson": {"videoLink": "https:\/\/www.loom.com\/share\/abc123", "title": "Intro", "position": 1},
+		"related": ["https://www.loom.com/embed/def456"]}}}`
+
+	videos := extractVideos(strings.Join(jsonStrings(content), "\n"), "")
+	if len(videos) != 2 || videos[0].VideoID != "abc123" || videos[1].VideoID != "def456" {
+		t.Errorf("videos from JSON strings = %+v, want abc123 and def456", videos)
+	}
+
+	if got := jsonStrings("not json"); got != nil {
+		t.Errorf("jsonStrings() of invalid JSON = %q, want nil", got)
+	}
+}
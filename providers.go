@@ -0,0 +1,238 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Video hosts videos can be embedded from. The names double as the extractor
+// keys of yt-dlp's download archive.
+const (
+	providerLoom    = "loom"
+	providerYouTube = "youtube"
+	providerVimeo   = "vimeo"
+	providerWistia  = "wistia"
+	providerSkool   = "skool"
+)
+
+// Forms a video URL can be found in
+const (
+	videoFormShare = "share"
+	videoFormEmbed = "embed"
+)
+
+// videoProvider recognizes the videos of one host. Pattern matches the URL
+// forms of its videos with the video ID in the first non-empty group, unless
+// the provider identifies its matches itself.
+type videoProvider struct {
+	Name    string
+	Label   string   // name shown to users
+	Hosts   []string // domains the host serves videos and players from
+	Pattern *regexp.Regexp
+	// Embed tells whether a matched URL is the embedded player rather than
+	// the page of the video
+	Embed func(match string) bool
+	// Canonical returns the URL of the video's page
+	Canonical func(id string) string
+	// Identify, when set, returns the ID and the URL to download for a match,
+	// or false when the match is not a video of this provider
+	Identify func(match string) (id, videoURL string, ok bool)
+}
+
+// videoProviders lists the supported hosts. A URL is attributed to the first
+// provider that matches it.
+var videoProviders = []videoProvider{
+	{
+		Name:      providerLoom,
+		Label:     "Loom",
+		Hosts:     []string{"loom.com"},
+		Pattern:   regexp.MustCompile(`https?://(?:www\.)?loom\.com/(?:share|embed)/([a-zA-Z0-9]+)`),
+		Embed:     containsAny("/embed/"),
+		Canonical: loomShareURL,
+	},
+	{
+		Name:    providerYouTube,
+		Label:   "YouTube",
+		Hosts:   []string{"youtube.com", "youtu.be", "youtube-nocookie.com"},
+		Pattern: regexp.MustCompile(`https?://(?:(?:www\.|m\.)?youtube(?:-nocookie)?\.com/(?:watch\?(?:[^"'\s<>]*?&(?:amp;)?)?v=|embed/|shorts/|live/)|youtu\.be/)([a-zA-Z0-9_-]{11})`),
+		Embed:   containsAny("/embed/"),
+		Canonical: func(id string) string {
+			return "https://www.youtube.com/watch?v=" + id
+		},
+	},
+	{
+		Name:    providerVimeo,
+		Label:   "Vimeo",
+		Hosts:   []string{"vimeo.com"},
+		Pattern: vimeoRegex,
+		Embed:   containsAny("player.vimeo.com"),
+		Canonical: func(id string) string {
+			return "https://vimeo.com/" + id
+		},
+		Identify: identifyVimeoVideo,
+	},
+	{
+		Name:    providerWistia,
+		Label:   "Wistia",
+		Hosts:   []string{"wistia.com", "wistia.net", "wi.st"},
+		Pattern: regexp.MustCompile(`https?://(?:fast\.wistia\.(?:net|com)/embed/(?:iframe|medias)/|[a-zA-Z0-9-]+\.wistia\.com/medias/|wi\.st/medias/)([a-zA-Z0-9]{10})`),
+		Embed:   containsAny("/embed/"),
+		Canonical: func(id string) string {
+			return "https://fast.wistia.net/embed/iframe/" + id
+		},
+	},
+	{
+		// Videos uploaded to Skool play from HLS playlists on Skool's video
+		// hosts. The playlist URL carries the access token, so it is kept.
+		Name:     providerSkool,
+		Label:    "Skool",
+		Pattern:  regexp.MustCompile(`https?://[a-zA-Z0-9.-]+(?::[0-9]+)?/[^\s"'<>\\]*?\.m3u8(?:\?[^\s"'<>\\]*)?`),
+		Embed:    func(string) bool { return true },
+		Identify: identifySkoolVideo,
+	},
+}
+
+// vimeoRegex matches Vimeo player and page URLs with the video ID and the
+// hash of unlisted videos, given as /<hash> or as the h parameter
+var vimeoRegex = regexp.MustCompile(`https?://(?:player\.vimeo\.com/video/|(?:www\.)?vimeo\.com/(?:video/)?)([0-9]+)(?:/([0-9a-f]+)|\?(?:[^"'\s<>]*?&(?:amp;)?)?h=([0-9a-f]+))?`)
+
+// identifyVimeoVideo keeps the hash of unlisted videos, without which they
+// cannot be downloaded. Embeds keep their player URL, since domain-restricted
+// videos can only be played there.
+func identifyVimeoVideo(match string) (id, videoURL string, ok bool) {
+	m := vimeoRegex.FindStringSubmatch(match)
+	if m == nil {
+		return "", "", false
+	}
+	id, hash := m[1], m[2]+m[3]
+	if strings.Contains(match, "player.vimeo.com") {
+		videoURL = "https://player.vimeo.com/video/" + id
+		if hash != "" {
+			videoURL += "?h=" + hash
+		}
+		return id, videoURL, true
+	}
+	videoURL = "https://vimeo.com/" + id
+	if hash != "" {
+		videoURL += "/" + hash
+	}
+	return id, videoURL, true
+}
+
+func containsAny(markers ...string) func(string) bool {
+	return func(s string) bool {
+		for _, marker := range markers {
+			if strings.Contains(s, marker) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// providerByName returns the provider with the given name
+func providerByName(name string) (videoProvider, bool) {
+	for _, provider := range videoProviders {
+		if provider.Name == name {
+			return provider, true
+		}
+	}
+	return videoProvider{}, false
+}
+
+// providerLabel returns the name of a provider as shown to users
+func providerLabel(name string) string {
+	if provider, ok := providerByName(name); ok {
+		return provider.Label
+	}
+	return name
+}
+
+// isProviderURL reports whether raw points at a domain a supported host
+// serves videos from
+func isProviderURL(raw string) bool {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return false
+	}
+	host := u.Hostname()
+	for _, provider := range videoProviders {
+		for _, domain := range provider.Hosts {
+			if host == domain || strings.HasSuffix(host, "."+domain) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func loomShareURL(id string) string {
+	return fmt.Sprintf("https://www.loom.com/share/%s", id)
+}
+
+// videoMatch is a video URL found in a text
+type videoMatch struct {
+	pos      int
+	provider videoProvider
+	id       string
+	url      string
+	videoURL string
+}
+
+// extractVideos finds the URLs of every supported host in html in page order.
+// Each video is reported once, in the form it first appeared in.
+func extractVideos(html, sourceURL string) []VideoRef {
+	var matches []videoMatch
+	for _, provider := range videoProviders {
+		for _, loc := range provider.Pattern.FindAllStringSubmatchIndex(html, -1) {
+			match := videoMatch{pos: loc[0], provider: provider, url: html[loc[0]:loc[1]]}
+			if provider.Identify != nil {
+				var ok bool
+				if match.id, match.videoURL, ok = provider.Identify(match.url); !ok {
+					continue
+				}
+			} else {
+				for i := 2; i < len(loc); i += 2 {
+					if loc[i] >= 0 {
+						match.id = html[loc[i]:loc[i+1]]
+						break
+					}
+				}
+				match.videoURL = provider.Canonical(match.id)
+			}
+			matches = append(matches, match)
+		}
+	}
+	// Stable, so a URL matched by two providers goes to the first one
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].pos < matches[j].pos
+	})
+
+	seen := make(map[string]bool)
+	var result []VideoRef
+	for _, match := range matches {
+		video := VideoRef{
+			Provider:    match.provider.Name,
+			VideoID:     match.id,
+			URL:         match.videoURL,
+			OriginalURL: match.url,
+			Form:        videoFormShare,
+			SourceURL:   sourceURL,
+		}
+		if seen[video.key()] {
+			continue
+		}
+		seen[video.key()] = true
+
+		if match.provider.Embed(match.url) {
+			video.Form = videoFormEmbed
+		}
+		video.Index = len(result) + 1
+		result = append(result, video)
+	}
+
+	return result
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestExtractVideos_Providers(t *testing.T) {
+	tests := []struct {
+		name     string
+		html     string
+		provider string
+		id       string
+		url      string
+		form     string
+	}{
+		{name: "YouTube watch", html: `<a href="https://www.youtube.com/watch?v=dQw4w9WgXcQ">`, provider: providerYouTube, id: "dQw4w9WgXcQ", url: "https://www.youtube.com/watch?v=dQw4w9WgXcQ", form: videoFormShare},
+		{name: "YouTube watch with parameters", html: `https://www.youtube.com/watch?feature=share&amp;v=dQw4w9WgXcQ&t=10`, provider: providerYouTube, id: "dQw4w9WgXcQ", url: "https://www.youtube.com/watch?v=dQw4w9WgXcQ", form: videoFormShare},
+		{name: "YouTube short link", html: `https://youtu.be/dQw4w9WgXcQ?si=x`, provider: providerYouTube, id: "dQw4w9WgXcQ", url: "https://www.youtube.com/watch?v=dQw4w9WgXcQ", form: videoFormShare},
+		{name: "YouTube embed", html: `<iframe src="https://www.youtube-nocookie.com/embed/dQw4w9WgXcQ?rel=0">`, provider: providerYouTube, id: "dQw4w9WgXcQ", url: "https://www.youtube.com/watch?v=dQw4w9WgXcQ", form: videoFormEmbed},
+		{name: "Vimeo page", html: `https://vimeo.com/76979871`, provider: providerVimeo, id: "76979871", url: "https://vimeo.com/76979871", form: videoFormShare},
+		{name: "Vimeo player", html: `<iframe src="https://player.vimeo.com/video/76979871">`, provider: providerVimeo, id: "76979871", url: "https://player.vimeo.com/video/76979871", form: videoFormEmbed},
+		{name: "Vimeo unlisted player", html: `<iframe src="https://player.vimeo.com/video/123456789?h=abcdef1234">`, provider: providerVimeo, id: "123456789", url: "https://player.vimeo.com/video/123456789?h=abcdef1234", form: videoFormEmbed},
+		{name: "Vimeo unlisted player with parameters", html: `https://player.vimeo.com/video/123456789?badge=0&amp;h=abcdef1234&amp;app_id=1`, provider: providerVimeo, id: "123456789", url: "https://player.vimeo.com/video/123456789?h=abcdef1234", form: videoFormEmbed},
+		{name: "Vimeo unlisted page", html: `https://vimeo.com/123456789/abcdef1234`, provider: providerVimeo, id: "123456789", url: "https://vimeo.com/123456789/abcdef1234", form: videoFormShare},
+		{name: "Wistia iframe", html: `<iframe src="https://fast.wistia.net/embed/iframe/e4a27b971d?videoFoam=true">`, provider: providerWistia, id: "e4a27b971d", url: "https://fast.wistia.net/embed/iframe/e4a27b971d", form: videoFormEmbed},
+		{name: "Wistia media page", html: `https://school.wistia.com/medias/e4a27b971d`, provider: providerWistia, id: "e4a27b971d", url: "https://fast.wistia.net/embed/iframe/e4a27b971d", form: videoFormShare},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			videos := extractVideos(tt.html, "")
+			if len(videos) != 1 {
+				t.Fatalf("extractVideos() found %d videos, want 1: %+v", len(videos), videos)
+			}
+			video := videos[0]
+			if video.Provider != tt.provider || video.VideoID != tt.id || video.URL != tt.url || video.Form != tt.form {
+				t.Errorf("extractVideos() = %+v, want %s video %s at %s in %s form", video, tt.provider, tt.id, tt.url, tt.form)
+			}
+		})
+	}
+}
+
+func TestExtractVideos_MixedHostsInPageOrder(t *testing.T) {
+	html := `<iframe src="https://player.vimeo.com/video/123456"></iframe>
+		<a href="https://www.loom.com/share/abc123">Loom</a>
+		<iframe src="https://www.youtube.com/embed/dQw4w9WgXcQ"></iframe>
+		<a href="https://vimeo.com/123456">same Vimeo video</a>
+		<a href="https://www.loom.com/share/123456">Loom video with a Vimeo-like ID</a>`
+
+	var keys []string
+	for _, video := range extractVideos(html, "") {
+		keys = append(keys, video.key())
+	}
+	expected := []string{"vimeo 123456", "loom abc123", "youtube dQw4w9WgXcQ", "loom 123456"}
+	if !reflect.DeepEqual(keys, expected) {
+		t.Errorf("extractVideos() = %q, want %q", keys, expected)
+	}
+}
+
+func TestIsProviderURL(t *testing.T) {
+	tests := []struct {
+		url      string
+		expected bool
+	}{
+		{url: "https://cdn.loom.com/sessions/thumbnails/abc.gif", expected: true},
+		{url: "https://www.youtube.com/embed/dQw4w9WgXcQ", expected: true},
+		{url: "https://i.ytimg.com/vi/dQw4w9WgXcQ/hq.jpg", expected: false},
+		{url: "https://player.vimeo.com/video/1", expected: true},
+		{url: "https://fast.wistia.net/embed/iframe/abc", expected: true},
+		{url: "https://notloom.com/share/abc", expected: false},
+		{url: "https://www.skool.com/school/classroom", expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			if got := isProviderURL(tt.url); got != tt.expected {
+				t.Errorf("isProviderURL(%q) = %v, want %v", tt.url, got, tt.expected)
+			}
+		})
+	}
+}
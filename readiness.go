@@ -0,0 +1,138 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/chromedp"
+)
+
+const (
+	// networkIdleTime is how long the network must stay quiet before a page
+	// counts as loaded
+	networkIdleTime = 500 * time.Millisecond
+	// maxIdleRequests is the number of requests that may stay open on an idle
+	// page, such as analytics beacons and long polls
+	maxIdleRequests = 2
+	readyPollTime   = 100 * time.Millisecond
+)
+
+// pageState is what a readiness script reports about the current page
+type pageState struct {
+	Rendered bool `json:"rendered"` // the page's content is in the DOM
+	Complete bool `json:"complete"` // nothing more is awaited, e.g. the video embed is attached
+}
+
+// skoolPageJS reports whether the Skool app has rendered its content and
+// whether a video embed has been attached to it
+const skoolPageJS = `(() => {
+	const root = document.querySelector('#__next') || document.body;
+	const rendered = document.readyState === 'complete' && !!root &&
+		root.querySelector('main, h1, h2, [class*="Content"], a[href*="/classroom"]') !== null;
+	const embed = document.querySelector('iframe[src*="loom.com/"], a[href*="loom.com/share/"], [src*="loom.com/embed/"], ' +
+		'iframe[src*="youtube.com/embed/"], iframe[src*="youtube-nocookie.com/"], iframe[src*="player.vimeo.com/"], ' +
+		'iframe[src*="wistia."], [class*="wistia_embed"], video') !== null;
+	return {rendered: rendered, complete: rendered && embed};
+})()`
+
+// loginResultJS reports whether the login form was answered, either by
+// leaving the login page or by showing an error
+const loginResultJS = `(() => {
+	const text = document.body ? document.body.textContent : '';
+	const answered = !window.location.href.includes('/login') ||
+		text.includes('Incorrect password') || text.includes('No account found for this email.');
+	return {rendered: document.readyState === 'complete', complete: answered};
+})()`
+
+// networkTracker follows the requests of a page to tell when its network
+// has gone quiet
+type networkTracker struct {
+	mu       sync.Mutex
+	now      func() time.Time
+	inflight map[network.RequestID]bool
+	lastBusy time.Time
+}
+
+func newNetworkTracker(now func() time.Time) *networkTracker {
+	return &networkTracker{now: now, inflight: make(map[network.RequestID]bool), lastBusy: now()}
+}
+
+// handle records a browser event. It is called from the browser's event
+// loop, so it must not block.
+func (t *networkTracker) handle(ev any) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	wasBusy := len(t.inflight) > maxIdleRequests
+	switch ev := ev.(type) {
+	case *network.EventRequestWillBeSent:
+		t.inflight[ev.RequestID] = true
+	case *network.EventLoadingFinished:
+		delete(t.inflight, ev.RequestID)
+	case *network.EventLoadingFailed:
+		delete(t.inflight, ev.RequestID)
+	default:
+		return
+	}
+	if wasBusy || len(t.inflight) > maxIdleRequests {
+		t.lastBusy = t.now()
+	}
+}
+
+// idleFor returns how long the network has been quiet
+func (t *networkTracker) idleFor() time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if len(t.inflight) > maxIdleRequests {
+		return 0
+	}
+	return t.now().Sub(t.lastBusy)
+}
+
+// isReady reports whether a page in state, whose network has been quiet for
+// idle, can be read
+func isReady(state pageState, idle time.Duration) bool {
+	return state.Complete || (state.Rendered && idle >= networkIdleTime)
+}
+
+// runAndWaitReady runs action, usually a navigation or a click, and then
+// waits until stateJS reports the page as ready or maxWait has passed. A page
+// that is still not ready by then is read as it is.
+func runAndWaitReady(action chromedp.Action, stateJS string, maxWait time.Duration) chromedp.ActionFunc {
+	return func(ctx context.Context) error {
+		tracker := newNetworkTracker(time.Now)
+		listenCtx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		chromedp.ListenTarget(listenCtx, tracker.handle)
+
+		if err := network.Enable().Do(ctx); err != nil {
+			return err
+		}
+		if err := action.Do(ctx); err != nil {
+			return err
+		}
+
+		deadline := time.Now().Add(maxWait)
+		for {
+			var state pageState
+			// The page may be between documents, so errors only mean it is
+			// not ready yet
+			if err := chromedp.Evaluate(stateJS, &state).Do(ctx); err == nil && isReady(state, tracker.idleFor()) {
+				return nil
+			}
+			if !time.Now().Before(deadline) {
+				slog.Debug("⏳ Page not ready in time, reading it anyway", "wait", maxWait)
+				return nil
+			}
+
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(readyPollTime):
+			}
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/chromedp/cdproto/network"
+)
+
+func TestIsReady(t *testing.T) {
+	tests := []struct {
+		name     string
+		state    pageState
+		idle     time.Duration
+		expected bool
+	}{
+		{name: "not rendered", state: pageState{}, idle: time.Minute, expected: false},
+		{name: "rendered while loading", state: pageState{Rendered: true}, idle: networkIdleTime / 2, expected: false},
+		{name: "rendered and idle", state: pageState{Rendered: true}, idle: networkIdleTime, expected: true},
+		{name: "embed attached while loading", state: pageState{Rendered: true, Complete: true}, expected: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isReady(tt.state, tt.idle); got != tt.expected {
+				t.Errorf("isReady(%+v, %v) = %v, want %v", tt.state, tt.idle, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestNetworkTracker(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	tracker := newNetworkTracker(func() time.Time { return now })
+
+	advance := func(d time.Duration) {
+		now = now.Add(d)
+	}
+	send := func(id string) {
+		tracker.handle(&network.EventRequestWillBeSent{RequestID: network.RequestID(id)})
+	}
+
+	advance(time.Second)
+	if idle := tracker.idleFor(); idle != time.Second {
+		t.Errorf("idleFor() without requests = %v, want 1s", idle)
+	}
+
+	// A few open requests, like analytics beacons, still count as idle
+	send("beacon-1")
+	send("beacon-2")
+	if idle := tracker.idleFor(); idle != time.Second {
+		t.Errorf("idleFor() with %d open requests = %v, want 1s", maxIdleRequests, idle)
+	}
+
+	send("api-1")
+	advance(time.Second)
+	if idle := tracker.idleFor(); idle != 0 {
+		t.Errorf("idleFor() while busy = %v, want 0", idle)
+	}
+
+	advance(100 * time.Millisecond)
+	tracker.handle(&network.EventLoadingFinished{RequestID: "api-1"})
+	advance(200 * time.Millisecond)
+	if idle := tracker.idleFor(); idle != 200*time.Millisecond {
+		t.Errorf("idleFor() after the page finished loading = %v, want 200ms", idle)
+	}
+
+	// Failed requests are no longer open either
+	send("api-2")
+	tracker.handle(&network.EventLoadingFailed{RequestID: "api-2"})
+	advance(300 * time.Millisecond)
+	if idle := tracker.idleFor(); idle != 300*time.Millisecond {
+		t.Errorf("idleFor() after a failed request = %v, want 300ms", idle)
+	}
+}
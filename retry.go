@@ -0,0 +1,165 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	defaultMaxAttempts  = 3
+	defaultRetryBackoff = 2 * time.Second
+	defaultRetryJitter  = 0.2
+	maxRetryBackoff     = time.Minute
+)
+
+// RetryPolicy controls how often and how fast failed page loads, logins and
+// downloads are retried. The backoff doubles after every failed attempt, up to
+// maxRetryBackoff, and is varied by up to Jitter (a fraction) either way.
+type RetryPolicy struct {
+	MaxAttempts int
+	Backoff     time.Duration
+	Jitter      float64
+}
+
+// permanentError marks an error that retrying cannot fix
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string {
+	return e.err.Error()
+}
+
+func (e *permanentError) Unwrap() error {
+	return e.err
+}
+
+// permanent marks err as not worth retrying
+func permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err: err}
+}
+
+// ytDlpError is returned when yt-dlp exits with an error. Output holds the end
+// of its stderr, which tells network errors apart from permanent ones since
+// yt-dlp uses the same exit code for both.
+type ytDlpError struct {
+	ExitCode int
+	Output   string
+}
+
+func (e *ytDlpError) Error() string {
+	return fmt.Sprintf("yt-dlp exited with code %d", e.ExitCode)
+}
+
+// ytDlpPermanentMarkers are yt-dlp error messages for videos that will not
+// become downloadable by trying again
+var ytDlpPermanentMarkers = []string{
+	"HTTP Error 401",
+	"HTTP Error 403",
+	"HTTP Error 404",
+	"HTTP Error 410",
+	"Private video",
+	"private video",
+	"Unsupported URL",
+	"does not exist",
+	"password",
+}
+
+// isRetryable reports whether err may go away when the operation is tried
+// again. Errors are retryable unless they are known to be permanent, such as
+// a 404 response, a private video or an unsupported stream.
+func isRetryable(err error) bool {
+	var permanentErr *permanentError
+	var statusErr *httpStatusError
+	var ytDlpErr *ytDlpError
+
+	switch {
+	case err == nil, errors.Is(err, context.Canceled):
+		return false
+	case errors.As(err, &permanentErr), errors.Is(err, errUnsupportedStream):
+		return false
+	case errors.As(err, &statusErr):
+		return statusErr.StatusCode == http.StatusRequestTimeout ||
+			statusErr.StatusCode == http.StatusTooManyRequests ||
+			statusErr.StatusCode >= 500
+	case errors.As(err, &ytDlpErr):
+		// Exit code 2 means yt-dlp was called with invalid options
+		if ytDlpErr.ExitCode == 2 {
+			return false
+		}
+		for _, marker := range ytDlpPermanentMarkers {
+			if strings.Contains(ytDlpErr.Output, marker) {
+				return false
+			}
+		}
+		return true
+	}
+
+	// Everything else, such as step timeouts, dropped connections and browser
+	// navigation errors, is worth another attempt
+	return true
+}
+
+// backoff returns how long to wait after the given failed attempt
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	d := p.Backoff
+	for i := 1; i < attempt && d < maxRetryBackoff; i++ {
+		d *= 2
+	}
+	d = min(d, maxRetryBackoff)
+	if p.Jitter > 0 {
+		d += time.Duration(float64(d) * p.Jitter * (2*rand.Float64() - 1))
+	}
+	return d
+}
+
+// retry calls fn until it succeeds, fails with an error that is not
+// retryable, or the policy runs out of attempts. Each retry is logged as a
+// warning, naming the operation with what.
+func retry(ctx context.Context, policy RetryPolicy, what string, logger *slog.Logger, fn func() error) error {
+	attempts := max(policy.MaxAttempts, 1)
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if err == nil || attempt >= attempts || ctx.Err() != nil || !isRetryable(err) {
+			return err
+		}
+
+		wait := policy.backoff(attempt)
+		logger.Warn("⚠️ "+what+" failed, retrying", "attempt", fmt.Sprintf("%d/%d", attempt, attempts), "error", err, "wait", wait.Round(100*time.Millisecond))
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+	}
+}
+
+// tailBuffer keeps the last limit bytes written to it
+type tailBuffer struct {
+	limit int
+	buf   []byte
+}
+
+func (t *tailBuffer) Write(p []byte) (int, error) {
+	t.buf = append(t.buf, p...)
+	if len(t.buf) > t.limit {
+		t.buf = t.buf[len(t.buf)-t.limit:]
+	}
+	return len(p), nil
+}
+
+func (t *tailBuffer) String() string {
+	return string(t.buf)
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"testing"
+	"time"
+)
+
+func TestIsRetryable(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		expected bool
+	}{
+		{name: "step timeout", err: fmt.Errorf("navigate: %w", context.DeadlineExceeded), expected: true},
+		{name: "cancelled", err: context.Canceled, expected: false},
+		{name: "server error", err: &httpStatusError{StatusCode: 503}, expected: true},
+		{name: "rate limited", err: &httpStatusError{StatusCode: 429}, expected: true},
+		{name: "not found", err: fmt.Errorf("resolve: %w", &httpStatusError{StatusCode: 404}), expected: false},
+		{name: "unsupported stream", err: fmt.Errorf("%w: encrypted HLS", errUnsupportedStream), expected: false},
+		{name: "marked permanent", err: permanent(errors.New("invalid credentials")), expected: false},
+		{name: "yt-dlp network error", err: &ytDlpError{ExitCode: 1, Output: "ERROR: Unable to download webpage: <urlopen error timed out>"}, expected: true},
+		{name: "yt-dlp private video", err: &ytDlpError{ExitCode: 1, Output: "ERROR: [loom] abc: HTTP Error 404: Not Found"}, expected: false},
+		{name: "yt-dlp invalid options", err: &ytDlpError{ExitCode: 2}, expected: false},
+		{name: "unknown error", err: errors.New("net::ERR_CONNECTION_RESET"), expected: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isRetryable(tt.err); got != tt.expected {
+				t.Errorf("isRetryable(%v) = %v, want %v", tt.err, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestRetry(t *testing.T) {
+	policy := RetryPolicy{MaxAttempts: 3, Backoff: time.Millisecond}
+	transient := &httpStatusError{StatusCode: 502}
+
+	tests := []struct {
+		name         string
+		errs         []error
+		wantAttempts int
+		wantErr      bool
+	}{
+		{name: "succeeds first time", errs: []error{nil}, wantAttempts: 1},
+		{name: "succeeds after transient errors", errs: []error{transient, transient, nil}, wantAttempts: 3},
+		{name: "gives up after max attempts", errs: []error{transient, transient, transient, nil}, wantAttempts: 3, wantErr: true},
+		{name: "stops on permanent error", errs: []error{&httpStatusError{StatusCode: 404}, nil}, wantAttempts: 1, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			attempts := 0
+			err := retry(context.Background(), policy, "Test", slog.New(slog.DiscardHandler), func() error {
+				attempts++
+				return tt.errs[attempts-1]
+			})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("retry() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if attempts != tt.wantAttempts {
+				t.Errorf("Expected %d attempts, got %d", tt.wantAttempts, attempts)
+			}
+		})
+	}
+}
+
+func TestRetry_StopsWhenCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	policy := RetryPolicy{MaxAttempts: 5, Backoff: time.Hour}
+
+	attempts := 0
+	err := retry(ctx, policy, "Test", slog.New(slog.DiscardHandler), func() error {
+		attempts++
+		cancel()
+		return errors.New("connection reset")
+	})
+	if err == nil || attempts != 1 {
+		t.Errorf("Expected one failed attempt, got %d attempts and error %v", attempts, err)
+	}
+}
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	policy := RetryPolicy{Backoff: time.Second}
+	expected := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second}
+	for i, want := range expected {
+		if got := policy.backoff(i + 1); got != want {
+			t.Errorf("backoff(%d) = %v, want %v", i+1, got, want)
+		}
+	}
+	if got := policy.backoff(20); got != maxRetryBackoff {
+		t.Errorf("backoff(20) = %v, want %v", got, maxRetryBackoff)
+	}
+
+	policy.Jitter = 0.5
+	for range 100 {
+		if got := policy.backoff(2); got < time.Second || got > 3*time.Second {
+			t.Fatalf("backoff(2) with jitter = %v, want between 1s and 3s", got)
+		}
+	}
+}
+
+func TestTailBuffer(t *testing.T) {
+	tail := &tailBuffer{limit: 5}
+	_, _ = tail.Write([]byte("hello "))
+	_, _ = tail.Write([]byte("world"))
+	if tail.String() != "world" {
+		t.Errorf("tailBuffer = %q, want %q", tail.String(), "world")
+	}
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// segmentStateSuffix names the sidecar file that records how far a segmented
+// download got, next to its .part file
+const segmentStateSuffix = ".part.state"
+
+// segmentState is the progress of a segmented download. Segment URLs carry
+// signatures that change with every session, so the stream is identified by
+// the path of its first segment and the segment count instead.
+type segmentState struct {
+	Stream   string `json:"stream"`
+	Segments int    `json:"segments"`
+	Done     int    `json:"done"`
+	Bytes    int64  `json:"bytes"`
+}
+
+// streamKey identifies a stream by the path of its first segment
+func streamKey(segments []string) string {
+	u, err := url.Parse(segments[0])
+	if err != nil {
+		return segments[0]
+	}
+	return u.Host + u.Path
+}
+
+// loadSegmentState returns the number of segments and bytes of target that an
+// earlier attempt already wrote, or zero when there is nothing to resume
+func loadSegmentState(target string, segments []string) (int, int64) {
+	content, err := os.ReadFile(target + segmentStateSuffix)
+	if err != nil {
+		return 0, 0
+	}
+	var state segmentState
+	if err := json.Unmarshal(content, &state); err != nil {
+		return 0, 0
+	}
+	if state.Stream != streamKey(segments) || state.Segments != len(segments) || state.Done > len(segments) {
+		return 0, 0
+	}
+
+	// The state is written after the segment, so the part file may be longer
+	// but never shorter than recorded
+	info, err := os.Stat(target + ".part")
+	if err != nil || info.Size() < state.Bytes {
+		return 0, 0
+	}
+	return state.Done, state.Bytes
+}
+
+func saveSegmentState(statePath string, state segmentState) error {
+	content, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	tmp := statePath + ".tmp"
+	if err := os.WriteFile(tmp, content, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, statePath)
+}
+
+// downloadSegments concatenates the given segments into target. Progress is
+// recorded in a sidecar state file after every segment, so a download that
+// fails or is interrupted resumes from the last complete segment on the next
+// attempt instead of starting over.
+func downloadSegments(ctx context.Context, client mediaFetcher, segments []string, target string, out io.Writer) error {
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+
+	partFile := target + ".part"
+	statePath := target + segmentStateSuffix
+	done, written := loadSegmentState(target, segments)
+
+	f, err := os.OpenFile(partFile, os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	if err := f.Truncate(written); err == nil {
+		_, err = f.Seek(written, io.SeekStart)
+	}
+	if err != nil {
+		_ = f.Close()
+		return err
+	}
+	if done > 0 {
+		_, _ = fmt.Fprintf(out, "[native] Resuming at segment %d/%d\n", done+1, len(segments))
+	}
+
+	state := segmentState{Stream: streamKey(segments), Segments: len(segments), Done: done, Bytes: written}
+	for i := done; i < len(segments); i++ {
+		n, err := writeSegment(ctx, client, segments[i], f)
+		if err != nil {
+			_ = f.Close()
+			_, _ = fmt.Fprintln(out)
+			return fmt.Errorf("segment %d: %w", i+1, err)
+		}
+
+		state.Done, state.Bytes = i+1, state.Bytes+n
+		if err := saveSegmentState(statePath, state); err != nil {
+			_ = f.Close()
+			return fmt.Errorf("error saving download state: %v", err)
+		}
+		_, _ = fmt.Fprintf(out, "\r[native] Segment %d/%d (%s)", i+1, len(segments), formatBytes(state.Bytes))
+	}
+	_, _ = fmt.Fprintln(out)
+
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(partFile, target); err != nil {
+		return err
+	}
+	_ = os.Remove(statePath)
+	return nil
+}
+
+// writeSegment appends one segment to w. A partially written segment is
+// overwritten when the download resumes, since only complete segments are
+// recorded in the state file.
+func writeSegment(ctx context.Context, client mediaFetcher, segmentURL string, w io.Writer) (int64, error) {
+	resp, err := client.get(ctx, segmentURL)
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	return io.Copy(w, resp.Body)
+}
+
+// muxToMP4 combines the given audio and video streams into an MP4 file at
+// target with ffmpeg, without re-encoding
+func muxToMP4(ctx context.Context, ffmpeg string, inputs []string, target string) error {
+	partFile := target + ".part"
+	args := []string{"-y", "-loglevel", "error"}
+	for _, input := range inputs {
+		args = append(args, "-i", input)
+	}
+	for i := range inputs {
+		args = append(args, "-map", fmt.Sprintf("%d", i))
+	}
+	args = append(args, "-c", "copy", "-f", "mp4", partFile)
+
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, ffmpeg, args...)
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		_ = os.Remove(partFile)
+		return fmt.Errorf("ffmpeg failed: %v: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return os.Rename(partFile, target)
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/chromedp"
+)
+
+// sessionCookieURLs are the sites whose cookies make up a logged in session
+var sessionCookieURLs = []string{skoolBaseURL, "https://www.loom.com/"}
+
+// sessionCachePath returns the file the browser session is cached in after
+// an email login, or "" when caching is turned off
+func (c Config) sessionCachePath() string {
+	if c.NoSessionCache || c.Email == "" {
+		return ""
+	}
+	if c.SessionCache != "" {
+		return c.SessionCache
+	}
+	return defaultSessionCachePath(c.Email)
+}
+
+// defaultSessionCachePath returns the session cache for an account in the
+// user cache directory, e.g. ~/.cache/skool-loom-dl/session-<hash>.json on
+// Linux. The file is named after a hash of the email so that several
+// accounts can be cached side by side without the address showing up in
+// file names.
+func defaultSessionCachePath(email string) string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(strings.ToLower(strings.TrimSpace(email))))
+	return filepath.Join(dir, configDirName, "session-"+hex.EncodeToString(sum[:8])+".json")
+}
+
+// scrapeWithSession scrapes with the session cached by an earlier login and
+// only logs in again when there is no usable cached session or Skool rejects it
+func scrapeWithSession(ctx context.Context, config Config) ([]VideoRef, []*network.Cookie, error) {
+	path := config.sessionCachePath()
+	if path == "" || !sessionCacheUsable(path) {
+		return scrapeWithLogin(ctx, config)
+	}
+
+	slog.Info("🍪 Reusing saved session", "path", path)
+	cached := config
+	cached.CookiesFile = path
+	videos, cookies, err := scrapeWithCookies(ctx, cached)
+	if !errors.Is(err, errAuthFailed) {
+		return videos, cookies, err
+	}
+
+	slog.Warn("⚠️ Saved session was rejected, logging in again", "error", err)
+	_ = os.Remove(path)
+	return scrapeWithLogin(ctx, config)
+}
+
+// sessionCacheUsable reports whether the cache file holds a Skool cookie that
+// has not expired yet
+func sessionCacheUsable(path string) bool {
+	cookies, err := parseCookiesFile(path)
+	if err != nil {
+		return false
+	}
+	now := time.Now()
+	for _, c := range cookies {
+		if !strings.HasSuffix(c.Domain, "skool.com") {
+			continue
+		}
+		if c.Expires == nil || c.Expires.Time().After(now) {
+			return true
+		}
+	}
+	return false
+}
+
+// browserCookies returns the Skool and Loom cookies of the browser session
+func browserCookies(ctx context.Context) ([]*network.Cookie, error) {
+	var cookies []*network.Cookie
+	err := runWithTimeout(ctx, chromedp.ActionFunc(func(ctx context.Context) error {
+		var err error
+		cookies, err = network.GetCookies().WithURLs(sessionCookieURLs).Do(ctx)
+		return err
+	}))
+	return cookies, err
+}
+
+// jsonCookies converts browser cookies to the JSON cookie file format
+func jsonCookies(cookies []*network.Cookie) []JSONCookie {
+	result := make([]JSONCookie, 0, len(cookies))
+	for _, c := range cookies {
+		cookie := JSONCookie{
+			Host:  c.Domain,
+			Name:  c.Name,
+			Value: c.Value,
+			Path:  c.Path,
+		}
+		if !c.Session && c.Expires > 0 {
+			cookie.Expiry = int64(c.Expires)
+		}
+		if c.Secure {
+			cookie.IsSecure = 1
+		}
+		if c.HTTPOnly {
+			cookie.IsHttpOnly = 1
+		}
+		cookie.SameSite = jsonSameSite(c.SameSite)
+		result = append(result, cookie)
+	}
+	return result
+}
+
+// jsonSameSite returns the JSON cookie file value for a SameSite setting
+func jsonSameSite(sameSite network.CookieSameSite) int {
+	switch sameSite {
+	case network.CookieSameSiteLax:
+		return 1
+	case network.CookieSameSiteStrict:
+		return 2
+	case network.CookieSameSiteNone:
+		return 3
+	}
+	return 0
+}
+
+// saveSessionCookies writes cookies as a JSON cookie file that only the
+// current user can read. The file is replaced atomically so an interrupted
+// write cannot leave a broken session behind.
+func saveSessionCookies(path string, cookies []*network.Cookie) error {
+	content, err := json.MarshalIndent(jsonCookies(cookies), "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+
+	tmp, err := writePrivateTempFile(filepath.Dir(path), filepath.Base(path)+".tmp-*", content)
+	if err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		_ = os.Remove(tmp)
+		return fmt.Errorf("error saving session: %v", err)
+	}
+	return nil
+}
+
+// exportSessionCookies writes the browser's cookies to a temporary Netscape
+// cookie file that only the current user can read, so that downloads run
+// with the same session as the browser. It returns the path of the file.
+func exportSessionCookies(cookies []*network.Cookie) (string, error) {
+	content, err := json.Marshal(jsonCookies(cookies))
+	if err != nil {
+		return "", err
+	}
+	jsonFile, err := writePrivateTempFile("", "skool-loom-dl-session-*.json", content)
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		_ = os.Remove(jsonFile)
+	}()
+	return convertJSONToNetscapeCookies(jsonFile)
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/chromedp/cdproto/network"
+)
+
+func TestSaveSessionCookies(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache", "session.json")
+	expires := float64(time.Now().Add(24 * time.Hour).Unix())
+	cookies := []*network.Cookie{
+		{Name: "auth_token", Value: "abc", Domain: ".skool.com", Path: "/", Expires: expires, Secure: true, HTTPOnly: true, SameSite: network.CookieSameSiteLax},
+		{Name: "loom_session", Value: "def", Domain: "www.loom.com", Path: "/", Session: true},
+	}
+
+	if err := saveSessionCookies(path, cookies); err != nil {
+		t.Fatalf("saveSessionCookies() error = %v", err)
+	}
+	if info, err := os.Stat(path); err != nil {
+		t.Fatalf("Failed to stat session cache: %v", err)
+	} else if runtime.GOOS != "windows" && info.Mode().Perm() != 0600 {
+		t.Errorf("session cache permissions = %04o, want 0600", info.Mode().Perm())
+	}
+
+	parsed, err := parseCookiesFile(path)
+	if err != nil {
+		t.Fatalf("parseCookiesFile() error = %v", err)
+	}
+	if len(parsed) != 2 {
+		t.Fatalf("parseCookiesFile() returned %d cookies, want 2", len(parsed))
+	}
+	auth := parsed[0]
+	if auth.Name != "auth_token" || auth.Value != "abc" || auth.Domain != "skool.com" || !auth.Secure || !auth.HTTPOnly || auth.SameSite != network.CookieSameSiteLax {
+		t.Errorf("auth cookie = %+v, want it to round-trip", auth)
+	}
+	if auth.Expires == nil || auth.Expires.Time().Unix() != int64(expires) {
+		t.Errorf("auth cookie expires = %v, want %v", auth.Expires, expires)
+	}
+	if parsed[1].Expires != nil {
+		t.Errorf("session cookie expires = %v, want none", parsed[1].Expires)
+	}
+}
+
+func TestSessionCacheUsable(t *testing.T) {
+	future := float64(time.Now().Add(time.Hour).Unix())
+	past := float64(time.Now().Add(-time.Hour).Unix())
+
+	tests := []struct {
+		name    string
+		cookies []*network.Cookie
+		want    bool
+	}{
+		{
+			name:    "Valid Skool cookie",
+			cookies: []*network.Cookie{{Name: "auth_token", Value: "a", Domain: ".skool.com", Path: "/", Expires: future}},
+			want:    true,
+		},
+		{
+			name:    "Skool session cookie",
+			cookies: []*network.Cookie{{Name: "auth_token", Value: "a", Domain: "www.skool.com", Path: "/", Session: true}},
+			want:    true,
+		},
+		{
+			name:    "Expired Skool cookie",
+			cookies: []*network.Cookie{{Name: "auth_token", Value: "a", Domain: ".skool.com", Path: "/", Expires: past}},
+		},
+		{
+			name:    "Only Loom cookies",
+			cookies: []*network.Cookie{{Name: "loom_session", Value: "a", Domain: ".loom.com", Path: "/", Expires: future}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "session.json")
+			if err := saveSessionCookies(path, tt.cookies); err != nil {
+				t.Fatalf("saveSessionCookies() error = %v", err)
+			}
+			if got := sessionCacheUsable(path); got != tt.want {
+				t.Errorf("sessionCacheUsable() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if sessionCacheUsable(filepath.Join(t.TempDir(), "missing.json")) {
+		t.Error("sessionCacheUsable() = true for a missing file")
+	}
+}
+
+func TestSessionCachePath(t *testing.T) {
+	if got := (Config{Email: "a@b.c", SessionCache: "session.json"}).sessionCachePath(); got != "session.json" {
+		t.Errorf("sessionCachePath() = %q, want the -session-cache path", got)
+	}
+	if got := (Config{Email: "a@b.c", NoSessionCache: true}).sessionCachePath(); got != "" {
+		t.Errorf("sessionCachePath() = %q with -no-session-cache, want none", got)
+	}
+	if got := (Config{CookiesFile: "cookies.json"}).sessionCachePath(); got != "" {
+		t.Errorf("sessionCachePath() = %q without email, want none", got)
+	}
+
+	if defaultSessionCachePath("A@B.c ") != defaultSessionCachePath("a@b.c") {
+		t.Error("defaultSessionCachePath() differs by case and whitespace of the email")
+	}
+	if defaultSessionCachePath("a@b.c") == defaultSessionCachePath("x@y.z") {
+		t.Error("defaultSessionCachePath() is the same for different accounts")
+	}
+}
+
+func TestExportSessionCookies(t *testing.T) {
+	cookies := []*network.Cookie{
+		{Name: "auth_token", Value: "abc", Domain: ".skool.com", Path: "/", Expires: 1900000000, Secure: true},
+		{Name: "connect.sid", Value: "def", Domain: "www.loom.com", Path: "/", Session: true, Secure: true},
+	}
+
+	path, err := exportSessionCookies(cookies)
+	if err != nil {
+		t.Fatalf("exportSessionCookies() error = %v", err)
+	}
+	defer func() {
+		_ = os.Remove(path)
+	}()
+
+	if info, err := os.Stat(path); err != nil {
+		t.Fatalf("Failed to stat cookie file: %v", err)
+	} else if runtime.GOOS != "windows" && info.Mode().Perm() != 0600 {
+		t.Errorf("cookie file permissions = %04o, want 0600", info.Mode().Perm())
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read cookie file: %v", err)
+	}
+	for _, want := range []string{
+		"# Netscape HTTP Cookie File",
+		".skool.com\tTRUE\t/\tTRUE\t1900000000\tauth_token\tabc",
+		".www.loom.com\tTRUE\t/\tTRUE\t0\tconnect.sid\tdef",
+	} {
+		if !strings.Contains(string(content), want) {
+			t.Errorf("cookie file does not contain %q:\n%s", want, content)
+		}
+	}
+
+	parsed, err := parseCookiesFile(path)
+	if err != nil || len(parsed) != 2 {
+		t.Errorf("parseCookiesFile() = %d cookies, %v, want 2", len(parsed), err)
+	}
+}
+
+func TestValidateConfig_EmailWithSavedSession(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "session.json")
+	config := Config{SkoolURL: "https://www.skool.com/school/classroom", Email: "a@b.c", SessionCache: path}
+	if err := validateConfig(config); err == nil {
+		t.Error("Expected error for email without password or saved session, got nil")
+	}
+
+	cookies := []*network.Cookie{{Name: "auth_token", Value: "a", Domain: ".skool.com", Path: "/", Session: true}}
+	if err := saveSessionCookies(path, cookies); err != nil {
+		t.Fatalf("saveSessionCookies() error = %v", err)
+	}
+	if err := validateConfig(config); err != nil {
+		t.Errorf("validateConfig() error = %v, want the saved session to be enough", err)
+	}
+}
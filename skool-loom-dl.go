@@ -3,32 +3,45 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
-	"log"
+	"io"
+	"log/slog"
 	"os"
 	"os/exec"
+	"os/signal"
 	"path/filepath"
-	"regexp"
 	"strings"
+	"syscall"
 	"time"
 
 	"github.com/chromedp/cdproto/cdp"
 	"github.com/chromedp/cdproto/network"
 	"github.com/chromedp/chromedp"
+	"golang.org/x/term"
 )
 
 const (
-	defaultWaitTime  = 2
-	defaultOutputDir = "downloads"
-	defaultHeadless  = true
-	browserTimeout   = 180 * time.Second
-	initialWaitTime  = 3 * time.Second
-	loginWaitTime    = 3 * time.Second
-	skoolBaseURL     = "https://www.skool.com/"
-	skoolLoginURL    = "https://www.skool.com/login"
+	defaultWaitTime    = 10
+	defaultOutputDir   = "downloads"
+	defaultHeadless    = true
+	defaultConcurrency = 1
+	browserTimeout     = 180 * time.Second
+	initialWaitTime    = 10 * time.Second
+	loginWaitTime      = 15 * time.Second
+	loginButtonTimeout = 5 * time.Second
+	processStopTimeout = 10 * time.Second
+	skoolBaseURL       = "https://www.skool.com/"
+	skoolLoginURL      = "https://www.skool.com/login"
 )
 
+const usage = "Usage: skool-loom-dl -url=https://skool.com/yourschool/classroom/path [-cookies=cookies.json | -email=user@example.com -password=pass]\n" +
+	"       skool-loom-dl -from=videos.txt [-cookies=cookies.json]"
+
+// errUsage is returned by validateConfig when neither -url nor -from is given
+var errUsage = errors.New("no URL or video list given")
+
 // JSONCookie represents a cookie in the JSON format
 type JSONCookie struct {
 	Host       string `json:"host"`
@@ -41,55 +54,238 @@ type JSONCookie struct {
 	SameSite   int    `json:"sameSite"`
 }
 
+// VideoRef is a video found on a Skool page, along with where it was found.
+// The lesson fields are empty for videos scraped outside a course crawl.
+type VideoRef struct {
+	Provider    string `json:"provider"` // host of the video, e.g. providerLoom
+	VideoID     string `json:"video_id"`
+	URL         string `json:"url"`          // canonical URL of the video's page
+	OriginalURL string `json:"original_url"` // URL as it appeared on the page
+	Form        string `json:"form"`         // videoFormShare or videoFormEmbed
+	SourceURL   string `json:"source_url"`   // page the video was found on
+	Index       int    `json:"index"`        // 1-based position among the videos of the source page
+
+	Community   string `json:"community,omitempty"`
+	Course      string `json:"course,omitempty"`
+	Module      string `json:"module,omitempty"`
+	ModuleIndex int    `json:"module_index,omitempty"`
+	LessonTitle string `json:"lesson_title,omitempty"`
+	LessonIndex int    `json:"lesson_index,omitempty"`
+}
+
+// key identifies the video across hosts, in the "<provider> <id>" form of
+// yt-dlp's download archive
+func (v VideoRef) key() string {
+	return v.Provider + " " + v.VideoID
+}
+
 // Config holds application configuration
 type Config struct {
-	SkoolURL    string
-	CookiesFile string
-	Email       string
-	Password    string
-	OutputDir   string
-	WaitTime    int
-	Headless    bool
+	SkoolURL       string
+	CookiesFile    string
+	CookiesFrom    string
+	Email          string
+	Password       string
+	PasswordFile   string
+	PasswordStdin  bool
+	SessionCache   string
+	NoSessionCache bool
+	CheckCookies   bool
+	OutputDir      string
+	WaitTime       int
+	Headless       bool
+	Crawl          bool
+	ArchiveFile    string
+	Force          bool
+	Concurrency    int
+	Export         string
+	ExportFile     string
+	From           string
+	Downloader     string
+	MaxAttempts    int
+	RetryDelay     time.Duration
+	RetryJitter    float64
+	SummaryFile    string
+	LogLevel       string
+	LogFormat      string
+	// Interactive is set when stdin is a terminal, so a missing password can
+	// be asked for once a login turns out to be needed
+	Interactive bool
+}
+
+// retryPolicy returns the retry settings for page loads, login and downloads
+func (c Config) retryPolicy() RetryPolicy {
+	return RetryPolicy{MaxAttempts: c.MaxAttempts, Backoff: c.RetryDelay, Jitter: c.RetryJitter}
 }
 
 func main() {
-	printBanner()
-	config := parseFlags()
-	validateConfig(config)
+	config, err := parseFlags(os.Args[1:], os.Getenv)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(exitError)
+	}
 
-	// Create output directory if it doesn't exist
-	if err := os.MkdirAll(config.OutputDir, 0755); err != nil {
-		log.Fatalf("Error creating output directory: %v", err)
+	// When the video list goes to stdout, keep it clean by sending all
+	// progress output to stderr instead
+	var progressOut io.Writer = os.Stdout
+	if config.Export != "" && config.ExportFile == "-" {
+		progressOut = os.Stderr
 	}
 
-	fmt.Println("🔍 Scraping Loom videos from:", config.SkoolURL)
+	// An invalid level is reported by validateConfig below
+	level, _ := parseLogLevel(config.LogLevel)
+	slog.SetDefault(newLogger(progressOut, level, config.LogFormat))
 
-	// Scrape videos based on auth method
-	loomURLs, err := scrapeVideos(config)
+	if config.LogFormat != logFormatJSON && level <= slog.LevelInfo {
+		printBanner(progressOut)
+	}
+	config.Interactive = term.IsTerminal(int(os.Stdin.Fd()))
+	cleanup, err := resolveCredentials(&config, os.Stdin)
 	if err != nil {
-		log.Fatalf("Error scraping: %v", err)
+		slog.Error("❌ Invalid credentials", "error", err)
+		os.Exit(exitError)
+	}
+	if err := validateConfig(config); err != nil {
+		if errors.Is(err, errUsage) {
+			_, _ = fmt.Fprintln(progressOut, usage)
+		} else {
+			slog.Error("❌ Invalid options", "error", err)
+		}
+		cleanup()
+		os.Exit(exitError)
 	}
 
-	if len(loomURLs) == 0 {
-		fmt.Println("❌ No Loom videos found. Check authentication and URL.")
-		return
+	// Stop scraping and downloads cleanly on Ctrl-C
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	code := run(ctx, config, os.Stdout)
+	stop()
+	cleanup()
+	os.Exit(code)
+}
+
+// run scrapes or reads the videos and downloads or exports them, returning
+// the exit code. With -summary set, the outcome is written to the summary
+// file however the run ends.
+func run(ctx context.Context, config Config, exportOut io.Writer) (code int) {
+	var results []downloadResult
+	var runErr error
+	if config.SummaryFile != "" {
+		startedAt := time.Now()
+		source := config.SkoolURL
+		if config.From != "" {
+			source = config.From
+		}
+		defer func() {
+			summary := newRunSummary(source, startedAt, code, runErr, results)
+			if err := writeSummary(config.SummaryFile, summary); err != nil {
+				slog.Error("❌ Error writing summary", "path", config.SummaryFile, "error", err)
+			}
+		}()
 	}
 
-	fmt.Printf("✅ Found %d Loom videos\n", len(loomURLs))
+	// fail reports an error that stops the run
+	fail := func(msg string, err error) int {
+		runErr = err
+		slog.Error("❌ "+msg, "error", err)
+		if ctx.Err() != nil {
+			return exitInterrupted
+		}
+		return exitCodeForError(err)
+	}
+
+	if config.CheckCookies {
+		return checkCookies(ctx, config)
+	}
+
+	var archive *downloadArchive
+	var downloader Downloader
+	if config.Export == "" {
+		// Create output directory if it doesn't exist
+		if err := os.MkdirAll(config.OutputDir, 0755); err != nil {
+			return fail("Error creating output directory", err)
+		}
+
+		if config.ArchiveFile == "" {
+			config.ArchiveFile = filepath.Join(config.OutputDir, defaultArchiveName)
+		}
+		var err error
+		if archive, err = openArchive(config.ArchiveFile); err != nil {
+			return fail("Error opening download archive", err)
+		}
+		if downloader, err = newDownloader(config); err != nil {
+			return fail("Error setting up downloader", err)
+		}
+	}
+
+	var videos []VideoRef
+	var err error
+	if config.From != "" {
+		slog.Info("📄 Reading videos", "from", config.From)
+		if videos, err = loadVideos(config.From); err != nil {
+			return fail("Error reading video list", err)
+		}
+	} else {
+		slog.Info("🔍 Scraping videos", "url", config.SkoolURL)
+
+		// Scrape videos based on auth method
+		var cookies []*network.Cookie
+		if videos, cookies, err = scrapeVideos(ctx, config); err != nil {
+			return fail("Error scraping", err)
+		}
+
+		// Without a cookies file, downloads would run logged out, so hand
+		// them the session of the browser that just logged in
+		if downloader != nil && config.CookiesFile == "" && len(cookies) > 0 {
+			cookiesFile, err := exportSessionCookies(cookies)
+			if err != nil {
+				return fail("Error exporting session cookies", err)
+			}
+			defer func() {
+				_ = os.Remove(cookiesFile)
+			}()
+			config.CookiesFile = cookiesFile
+			if downloader, err = newDownloader(config); err != nil {
+				return fail("Error setting up downloader", err)
+			}
+			slog.Debug("🍪 Downloading with the browser session", "cookies", len(cookies))
+		}
+	}
 
-	// Download each video
-	for i, url := range loomURLs {
-		fmt.Printf("\n[%d/%d] 📥 Downloading: %s\n", i+1, len(loomURLs), url)
-		if err := downloadWithYtDlp(url, config.CookiesFile, config.OutputDir); err != nil {
-			fmt.Printf("❌ Error: %v\n", err)
+	if config.Export != "" {
+		if err := exportVideos(videos, config, exportOut); err != nil {
+			return fail("Error exporting videos", err)
+		}
+		slog.Info("✅ Exported videos", "count", len(videos))
+		if len(videos) == 0 {
+			return exitNothingFound
 		}
+		return exitOK
 	}
 
-	fmt.Println("\n✅ Download process completed!")
+	if len(videos) == 0 {
+		slog.Error("❌ No videos found. Check authentication and URL.")
+		return exitNothingFound
+	}
+
+	slog.Info("✅ Found videos", "count", len(videos))
+	slog.Info("⬇️ Starting downloads", "downloader", downloader.Name())
+	results = downloadAll(ctx, videos, downloader, config, archive)
+	printDownloadSummary(results)
+
+	code = exitCodeForResults(results, ctx.Err() != nil)
+	switch code {
+	case exitInterrupted:
+		slog.Warn("⚠️ Download process interrupted")
+	case exitOK:
+		slog.Info("✅ Download process completed!")
+	default:
+		slog.Error("❌ Download process completed with errors")
+	}
+	return code
 }
 
-func printBanner() {
-	fmt.Println(`
+func printBanner(w io.Writer) {
+	_, _ = fmt.Fprintln(w, `
     ╔═══╗╔╗   ╔═══╗
     ║╔═╗║║║   ║╔═╗║
     ║╚══╗║║   ║║ ║║
@@ -100,44 +296,122 @@ func printBanner() {
     `)
 }
 
-func parseFlags() Config {
+// parseFlags builds the configuration from the command line, a profile of the
+// config file and environment variables. Flags override the config file, and
+// environment variables override both.
+func parseFlags(args []string, getenv func(string) string) (Config, error) {
 	config := Config{}
+	flags := flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+
+	flags.StringVar(&config.SkoolURL, "url", "", "URL of the skool.com classroom to scrape (required)")
+	flags.StringVar(&config.CookiesFile, "cookies", "", "Path to cookies file (JSON or TXT) for authentication (- for stdin)")
+	flags.StringVar(&config.CookiesFrom, "cookies-from-browser", "", "Read the Skool and Loom cookies from a local browser profile: firefox, chrome or chromium, optionally followed by :profile")
+	flags.StringVar(&config.Email, "email", "", "Email for Skool login (alternative to cookies)")
+	flags.StringVar(&config.Password, "password", "", "Password for Skool login (visible to other users in the process list, prefer the alternatives below)")
+	flags.StringVar(&config.PasswordFile, "password-file", "", "Read the Skool password from the first line of this file, which must not be readable by other users")
+	flags.BoolVar(&config.PasswordStdin, "password-stdin", false, "Read the Skool password from the first line of stdin")
+	flags.BoolVar(&config.CheckCookies, "check-cookies", false, "Only report the Skool and Loom cookies of the cookies file and check that Skool accepts them, for the -url page if given")
+	flags.StringVar(&config.SessionCache, "session-cache", "", "File to keep the browser session in after an email login, reused by later runs (default: in the user cache directory)")
+	flags.BoolVar(&config.NoSessionCache, "no-session-cache", false, "Log in with email and password on every run instead of reusing the saved session")
+	flags.StringVar(&config.OutputDir, "output", defaultOutputDir, "Directory to save downloaded videos")
+	flags.IntVar(&config.WaitTime, "wait", defaultWaitTime, "Maximum time in seconds to wait for a page to finish loading")
+	flags.BoolVar(&config.Headless, "headless", defaultHeadless, "Run in headless mode (no browser UI)")
+	flags.BoolVar(&config.Crawl, "crawl", false, "Crawl every lesson of the course instead of only the given page")
+	flags.StringVar(&config.ArchiveFile, "archive", "", "Path to the download archive of finished videos (default: <output>/"+defaultArchiveName+")")
+	flags.BoolVar(&config.Force, "force", false, "Download videos again even if they are in the download archive")
+	flags.IntVar(&config.Concurrency, "concurrency", defaultConcurrency, "Number of videos to download in parallel")
+	flags.StringVar(&config.Export, "export", "", "Only list the videos found instead of downloading them, as txt, json or csv")
+	flags.StringVar(&config.ExportFile, "export-file", "-", "File to write the video list to with -export (- for stdout)")
+	flags.StringVar(&config.Downloader, "downloader", downloaderAuto, "Download backend: auto (native with yt-dlp fallback), native, yt-dlp or dry-run")
+	flags.IntVar(&config.MaxAttempts, "max-attempts", defaultMaxAttempts, "Maximum attempts for each page load, login and download before giving up")
+	flags.DurationVar(&config.RetryDelay, "retry-delay", defaultRetryBackoff, "Wait before the first retry, doubled after every further failure")
+	flags.Float64Var(&config.RetryJitter, "retry-jitter", defaultRetryJitter, "Random variation of the retry delay, as a fraction of it (0 to 1)")
+	flags.StringVar(&config.SummaryFile, "summary", "", "Write a JSON summary of the run with the outcome of every video to this file")
+	flags.StringVar(&config.LogLevel, "log-level", logLevelInfo, "Log verbosity: quiet (warnings and errors only), info, debug or trace")
+	flags.StringVar(&config.LogFormat, "log-format", logFormatText, "Log format: text or json (one JSON object per line)")
+	flags.StringVar(&config.From, "from", "", "Download the videos listed in a file written by -export (txt or json, - for stdin) instead of scraping")
+
+	var configFile, profile string
+	flags.StringVar(&configFile, "config", "", "Path to the config file (default: "+defaultConfigPathHint+")")
+	flags.StringVar(&profile, "profile", "", "Name of the config file profile to use")
+
+	if err := flags.Parse(args); err != nil {
+		return config, err
+	}
+	if err := applyConfigSources(flags, configFile, profile, getenv); err != nil {
+		return config, err
+	}
+	return config, nil
+}
 
-	flag.StringVar(&config.SkoolURL, "url", "", "URL of the skool.com classroom to scrape (required)")
-	flag.StringVar(&config.CookiesFile, "cookies", "", "Path to cookies file (JSON or TXT) for authentication")
-	flag.StringVar(&config.Email, "email", "", "Email for Skool login (alternative to cookies)")
-	flag.StringVar(&config.Password, "password", "", "Password for Skool login (required with email)")
-	flag.StringVar(&config.OutputDir, "output", defaultOutputDir, "Directory to save downloaded videos")
-	flag.IntVar(&config.WaitTime, "wait", defaultWaitTime, "Time to wait for page to load in seconds")
-	flag.BoolVar(&config.Headless, "headless", defaultHeadless, "Run in headless mode (no browser UI)")
+// validateConfig checks that the flags make sense for the selected mode.
+// Scraping needs a URL and credentials; downloading from a video list with
+// -from needs neither, since no browser is started.
+func validateConfig(config Config) error {
+	if config.Export != "" && !isExportFormat(config.Export) {
+		return fmt.Errorf("unknown export format %q, use txt, json or csv", config.Export)
+	}
+	if config.Downloader != "" && !isDownloaderName(config.Downloader) {
+		return fmt.Errorf("unknown downloader %q, use auto, native, yt-dlp or dry-run", config.Downloader)
+	}
 
-	flag.Parse()
-	return config
-}
+	if _, err := parseLogLevel(config.LogLevel); err != nil {
+		return err
+	}
+	if !isLogFormat(config.LogFormat) {
+		return fmt.Errorf("unknown log format %q, use text or json", config.LogFormat)
+	}
+	if config.MaxAttempts < 0 || config.RetryDelay < 0 || config.RetryJitter < 0 || config.RetryJitter > 1 {
+		return fmt.Errorf("-max-attempts and -retry-delay must not be negative and -retry-jitter must be between 0 and 1")
+	}
+
+	if config.CheckCookies {
+		if config.CookiesFile == "" && config.CookiesFrom == "" {
+			return fmt.Errorf("-check-cookies needs a cookies file given with -cookies or -cookies-from-browser")
+		}
+		if config.From != "" || config.Export != "" {
+			return fmt.Errorf("-check-cookies cannot be used with -from or -export")
+		}
+		return nil
+	}
+
+	if config.From != "" {
+		if config.SkoolURL != "" {
+			return fmt.Errorf("-url and -from cannot be used together")
+		}
+		return nil
+	}
 
-func validateConfig(config Config) {
 	if config.SkoolURL == "" {
-		fmt.Println("Usage: skool-loom-dl -url=https://skool.com/yourschool/classroom/path [-cookies=cookies.json | -email=user@example.com -password=pass]")
-		os.Exit(1)
+		return errUsage
 	}
 
-	usingEmail := config.Email != "" && config.Password != ""
-	usingCookies := config.CookiesFile != ""
+	// A usable saved session needs no password until Skool rejects it
+	havePassword := config.Password != "" || config.PasswordFile != "" || config.PasswordStdin || config.Interactive
+	usingEmail := config.Email != "" && (havePassword || sessionCacheUsable(config.sessionCachePath()))
+	usingCookies := config.CookiesFile != "" || config.CookiesFrom != ""
 
 	if !usingEmail && !usingCookies {
-		fmt.Println("Error: You must provide either cookies file or email+password for authentication")
-		os.Exit(1)
+		return fmt.Errorf("you must provide either cookies file or email+password for authentication")
 	}
+	return nil
 }
 
-func scrapeVideos(config Config) ([]string, error) {
-	if config.Email != "" && config.Password != "" {
-		return scrapeWithLogin(config)
+// scrapeVideos scrapes the configured URL. After an email login it also
+// returns the cookies of the browser session, for downloads that need them.
+// Cookies are used instead when they are given without a password.
+func scrapeVideos(ctx context.Context, config Config) ([]VideoRef, []*network.Cookie, error) {
+	havePassword := config.Password != "" || config.PasswordFile != "" || config.PasswordStdin
+	if config.Email != "" && (havePassword || config.CookiesFile == "") {
+		return scrapeWithSession(ctx, config)
 	}
-	return scrapeWithCookies(config)
+	videos, _, err := scrapeWithCookies(ctx, config)
+	return videos, nil, err
 }
 
-func setupBrowser(headless bool) (context.Context, context.CancelFunc) {
+// setupBrowser starts a browser whose lifetime is bound to parent. The browser
+// is launched right away so later per-step timeouts cannot close it.
+func setupBrowser(parent context.Context, headless bool) (context.Context, context.CancelFunc, error) {
 	opts := append(chromedp.DefaultExecAllocatorOptions[:],
 		chromedp.Flag("headless", headless),
 		chromedp.Flag("disable-gpu", true),
@@ -146,199 +420,292 @@ func setupBrowser(headless bool) (context.Context, context.CancelFunc) {
 		chromedp.UserAgent("Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/91.0.4472.124 Safari/537.36"),
 	)
 
-	allocCtx, cancel := chromedp.NewExecAllocator(context.Background(), opts...)
-	ctx, cancel2 := chromedp.NewContext(allocCtx, chromedp.WithLogf(log.Printf))
-	ctx, cancel3 := context.WithTimeout(ctx, browserTimeout)
+	allocCtx, cancel := chromedp.NewExecAllocator(parent, opts...)
+	// Browser messages are only of interest when debugging, and the raw
+	// protocol traffic only when tracing
+	ctx, cancel2 := chromedp.NewContext(allocCtx,
+		chromedp.WithLogf(chromedpLogf(slog.LevelDebug)),
+		chromedp.WithErrorf(chromedpLogf(slog.LevelDebug)),
+		chromedp.WithDebugf(chromedpLogf(levelTrace)),
+	)
 
-	// Return a cancel function that calls all three cancel functions
-	return ctx, func() {
-		cancel3()
+	// Return a cancel function that calls both cancel functions
+	cancelAll := func() {
 		cancel2()
 		cancel()
 	}
-}
 
-func extractLoomURLs(html string) []string {
-	shareRegex := regexp.MustCompile(`https?://(?:www\.)?loom\.com/share/[a-zA-Z0-9]+`)
-	embedRegex := regexp.MustCompile(`https?://(?:www\.)?loom\.com/embed/([a-zA-Z0-9]+)`)
+	if err := chromedp.Run(ctx); err != nil {
+		cancelAll()
+		return nil, nil, fmt.Errorf("failed to start browser: %v", err)
+	}
+	return ctx, cancelAll, nil
+}
 
-	matches := shareRegex.FindAllString(html, -1)
+// runWithTimeout runs actions in the browser, giving up after browserTimeout
+// so a stuck page cannot hang a long crawl
+func runWithTimeout(ctx context.Context, actions ...chromedp.Action) error {
+	ctx, cancel := context.WithTimeout(ctx, browserTimeout)
+	defer cancel()
+	return chromedp.Run(ctx, actions...)
+}
 
-	// Convert embed URLs to share URLs
-	embedMatches := embedRegex.FindAllStringSubmatch(html, -1)
-	for _, match := range embedMatches {
-		if len(match) >= 2 {
-			shareURL := fmt.Sprintf("https://www.loom.com/share/%s", match[1])
-			matches = append(matches, shareURL)
+// uniqueVideos drops repeated videos, keeping the first place each was found
+func uniqueVideos(videos []VideoRef) []VideoRef {
+	seen := make(map[string]bool)
+	var result []VideoRef
+	for _, video := range videos {
+		if !seen[video.key()] {
+			seen[video.key()] = true
+			result = append(result, video)
 		}
 	}
+	return result
+}
+
+func scrapeWithLogin(parent context.Context, config Config) ([]VideoRef, []*network.Cookie, error) {
+	if err := requirePassword(&config, os.Stdin); err != nil {
+		return nil, nil, err
+	}
+
+	ctx, cancel, err := setupBrowser(parent, config.Headless)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer cancel()
+
+	slog.Info("🔑 Attempting login with email and password...")
+	if err := retry(ctx, config.retryPolicy(), "Login", slog.Default(), func() error {
+		return login(ctx, config)
+	}); err != nil {
+		return nil, nil, err
+	}
 
-	// Remove duplicates
-	uniqueURLs := make(map[string]bool)
-	var result []string
-	for _, url := range matches {
-		if !uniqueURLs[url] {
-			uniqueURLs[url] = true
-			result = append(result, url)
+	if path := config.sessionCachePath(); path != "" {
+		cookies, err := browserCookies(ctx)
+		if err == nil {
+			err = saveSessionCookies(path, cookies)
+		}
+		if err != nil {
+			slog.Warn("⚠️ Could not save session for later runs", "error", err)
+		} else {
+			slog.Debug("🍪 Saved session", "path", path, "cookies", len(cookies))
 		}
 	}
 
-	return result
+	return scrapeSession(ctx, config)
 }
 
-func scrapeWithLogin(config Config) ([]string, error) {
-	ctx, cancel := setupBrowser(config.Headless)
-	defer cancel()
-
+// login signs in to Skool with the configured email and password. Rejected
+// credentials are reported as a permanent error.
+func login(ctx context.Context, config Config) error {
 	var currentURL string
 	var loginSuccess bool
 
-	fmt.Println("🔑 Attempting login with email and password...")
-
 	// Navigate to the main Skool site
-	if err := chromedp.Run(ctx, chromedp.Tasks{
-		chromedp.Navigate(skoolBaseURL),
-		chromedp.Sleep(initialWaitTime),
+	if err := runWithTimeout(ctx, chromedp.Tasks{
+		runAndWaitReady(chromedp.Navigate(skoolBaseURL), skoolPageJS, initialWaitTime),
 		chromedp.Location(&currentURL),
 	}); err != nil {
-		return nil, fmt.Errorf("failed to navigate to Skool: %v", err)
+		return fmt.Errorf("failed to navigate to Skool: %w", err)
 	}
 
-	fmt.Println("📍 Landed on:", currentURL)
+	slog.Info("📍 Landed on", "url", currentURL)
 
-	// Try to find and click the login button
-	err := chromedp.Run(ctx, chromedp.Tasks{
-		chromedp.WaitVisible(`//button[@type="button"]/span[text()="Log In"]`, chromedp.BySearch),
-		chromedp.Click(`//button[@type="button"]/span[text()="Log In"]`, chromedp.BySearch),
-		chromedp.Sleep(2 * time.Second),
-		chromedp.Location(&currentURL),
-	})
+	// Try to find and click the login button. The page has loaded by now, so
+	// a button that isn't there soon won't show up at all.
+	probeCtx, cancelProbe := context.WithTimeout(ctx, loginButtonTimeout)
+	err := chromedp.Run(probeCtx, chromedp.WaitVisible(`//button[@type="button"]/span[text()="Log In"]`, chromedp.BySearch))
+	cancelProbe()
+	if err == nil {
+		err = runWithTimeout(ctx, chromedp.Tasks{
+			runAndWaitReady(chromedp.Click(`//button[@type="button"]/span[text()="Log In"]`, chromedp.BySearch), skoolPageJS, initialWaitTime),
+			chromedp.Location(&currentURL),
+		})
+	}
 
 	// If login button not found, navigate directly to login page
 	if err != nil {
-		fmt.Println("⚠️ Couldn't find login button, trying direct navigation to login page...")
-		if err := chromedp.Run(ctx, chromedp.Tasks{
-			chromedp.Navigate(skoolLoginURL),
-			chromedp.Sleep(initialWaitTime),
+		slog.Warn("⚠️ Couldn't find login button, trying direct navigation to login page...")
+		if err := runWithTimeout(ctx, chromedp.Tasks{
+			runAndWaitReady(chromedp.Navigate(skoolLoginURL), skoolPageJS, initialWaitTime),
 			chromedp.Location(&currentURL),
 		}); err != nil {
-			return nil, fmt.Errorf("couldn't access login page: %v", err)
+			return fmt.Errorf("couldn't access login page: %w", err)
 		}
 	}
 
-	fmt.Println("📍 Login page:", currentURL)
+	slog.Info("📍 Login page", "url", currentURL)
 
 	// Complete the login form
-	if err := chromedp.Run(ctx, chromedp.Tasks{
+	if err := runWithTimeout(ctx, chromedp.Tasks{
 		chromedp.WaitVisible(`//input[@type="email" or @name="email" or contains(@placeholder, "email")]`, chromedp.BySearch),
 		chromedp.SendKeys(`//input[@type="email" or @name="email" or contains(@placeholder, "email")]`, config.Email, chromedp.BySearch),
 
 		chromedp.WaitVisible(`//input[@type="password" or @name="password" or contains(@placeholder, "password")]`, chromedp.BySearch),
 		chromedp.SendKeys(`//input[@type="password" or @name="password" or contains(@placeholder, "password")]`, config.Password, chromedp.BySearch),
 
-		chromedp.Click(`//button[@type="submit" and .//span[contains(text(), "Log") or contains(text(), "Log In") or contains(text(), "Login")]]`, chromedp.BySearch),
+		runAndWaitReady(chromedp.Click(`//button[@type="submit" and .//span[contains(text(), "Log") or contains(text(), "Log In") or contains(text(), "Login")]]`, chromedp.BySearch), loginResultJS, loginWaitTime),
 
-		chromedp.Sleep(loginWaitTime),
 		chromedp.Location(&currentURL),
 		chromedp.Evaluate(`!window.location.href.includes('/login') && !document.body.textContent.includes('Incorrect password') && !document.body.textContent.includes('No account found for this email.')`, &loginSuccess),
 	}); err != nil {
-		return nil, fmt.Errorf("login process failed: %v", err)
+		return fmt.Errorf("login process failed: %w", err)
 	}
 
 	if !loginSuccess {
-		return nil, fmt.Errorf("login failed: invalid credentials or captcha required")
+		return permanent(fmt.Errorf("%w: invalid credentials or captcha required", errAuthFailed))
 	}
 
-	fmt.Println("✅ Login successful! Redirected to:", currentURL)
-	return navigateAndScrape(ctx, config.SkoolURL, config.WaitTime)
+	slog.Info("✅ Login successful!", "url", currentURL)
+	return nil
 }
 
-func scrapeWithCookies(config Config) ([]string, error) {
-	ctx, cancel := setupBrowser(config.Headless)
+func scrapeWithCookies(parent context.Context, config Config) ([]VideoRef, []*network.Cookie, error) {
+	ctx, cancel, err := setupBrowser(parent, config.Headless)
+	if err != nil {
+		return nil, nil, err
+	}
 	defer cancel()
 
 	// Load and set cookies
 	cookies, err := parseCookiesFile(config.CookiesFile)
 	if err != nil {
-		return nil, fmt.Errorf("error parsing cookies: %v", err)
+		return nil, nil, fmt.Errorf("error parsing cookies: %v", err)
 	}
 
 	// Log cookie info
-	fmt.Println("🍪 Setting cookies...")
+	slog.Info("🍪 Setting cookies...")
 	for _, c := range cookies {
 		if c.Name == "auth_token" && strings.Contains(c.Domain, "skool") {
 			truncatedValue := c.Value
 			if len(truncatedValue) > 20 {
 				truncatedValue = truncatedValue[:20] + "..."
 			}
-			fmt.Printf("  🔑 Auth token found: %s\n", truncatedValue)
+			slog.Info("🔑 Auth token found", "token", truncatedValue)
 		}
 	}
 
 	// Enable network and set cookies
-	if err := chromedp.Run(ctx, network.Enable()); err != nil {
-		return nil, err
+	if err := runWithTimeout(ctx, network.Enable()); err != nil {
+		return nil, nil, err
 	}
 
-	if err := chromedp.Run(ctx, network.SetCookies(cookies)); err != nil {
-		return nil, fmt.Errorf("error setting cookies: %v", err)
+	if err := runWithTimeout(ctx, network.SetCookies(cookies)); err != nil {
+		return nil, nil, fmt.Errorf("error setting cookies: %v", err)
 	}
 
 	var currentURL string
 	// Set headers and navigate first to main site, then to target URL
-	err = chromedp.Run(ctx, chromedp.Tasks{
-		network.SetExtraHTTPHeaders(network.Headers{
-			"Referer":         skoolBaseURL,
-			"Accept":          "text/html,application/xhtml+xml,application/xml",
-			"Accept-Language": "en-US,en;q=0.9",
-			"Connection":      "keep-alive",
-		}),
-		chromedp.Navigate(skoolBaseURL),
-		chromedp.Sleep(initialWaitTime),
-		chromedp.Location(&currentURL),
+	err = retry(ctx, config.retryPolicy(), "Loading "+skoolBaseURL, slog.Default(), func() error {
+		return runWithTimeout(ctx, chromedp.Tasks{
+			network.SetExtraHTTPHeaders(network.Headers{
+				"Referer":         skoolBaseURL,
+				"Accept":          "text/html,application/xhtml+xml,application/xml",
+				"Accept-Language": "en-US,en;q=0.9",
+				"Connection":      "keep-alive",
+			}),
+			runAndWaitReady(chromedp.Navigate(skoolBaseURL), skoolPageJS, initialWaitTime),
+			chromedp.Location(&currentURL),
+		})
 	})
 
 	if err != nil {
-		return nil, fmt.Errorf("failed to navigate to main site: %v", err)
+		return nil, nil, fmt.Errorf("failed to navigate to main site: %v", err)
 	}
 
-	fmt.Printf("🌐 Initial navigation landed on: %s\n", currentURL)
-	return navigateAndScrape(ctx, config.SkoolURL, config.WaitTime)
+	slog.Info("🌐 Initial navigation landed on", "url", currentURL)
+	return scrapeSession(ctx, config)
 }
 
-func navigateAndScrape(ctx context.Context, targetURL string, waitTime int) ([]string, error) {
-	var currentURL, html string
+// scrapeSession scrapes the configured URL and then returns the cookies of
+// the browser session along with the videos. These include the Loom cookies
+// set by embedded players while the pages were visited.
+func scrapeSession(ctx context.Context, config Config) ([]VideoRef, []*network.Cookie, error) {
+	videos, err := scrapeTarget(ctx, config)
+	if err != nil {
+		return nil, nil, err
+	}
+	cookies, err := browserCookies(ctx)
+	if err != nil {
+		slog.Warn("⚠️ Could not read the browser session cookies", "error", err)
+	}
+	return videos, cookies, nil
+}
 
-	fmt.Println("🏫 Navigating to classroom:", targetURL)
-	if err := chromedp.Run(ctx, chromedp.Tasks{
-		chromedp.Navigate(targetURL),
-		chromedp.Sleep(time.Duration(waitTime) * time.Second),
-		chromedp.Location(&currentURL),
-	}); err != nil {
+// scrapeTarget scrapes the configured URL in an authenticated browser context,
+// crawling every course of a community when given its classroom index and the
+// whole course when requested
+func scrapeTarget(ctx context.Context, config Config) ([]VideoRef, error) {
+	if isClassroomIndex(config.SkoolURL) {
+		results, err := crawlCommunity(ctx, config.SkoolURL, config.WaitTime, config.retryPolicy())
+		if err != nil {
+			return nil, err
+		}
+
+		lessons := courseLessons(results)
+		printLessonReport(lessons)
+		printCourseReport(results)
+		return lessonVideos(lessons), nil
+	}
+
+	if !config.Crawl {
+		return navigateAndScrape(ctx, config.SkoolURL, config.WaitTime, config.retryPolicy())
+	}
+
+	lessons, err := crawlCourse(ctx, config.SkoolURL, "", config.WaitTime, config.retryPolicy())
+	if err != nil {
+		return nil, err
+	}
+
+	printLessonReport(lessons)
+	return lessonVideos(lessons), nil
+}
+
+// navigate loads targetURL, waits up to waitTime seconds for the page to
+// finish loading and returns the final location. Failed page loads are
+// retried according to policy.
+func navigate(ctx context.Context, targetURL string, waitTime int, policy RetryPolicy) (string, error) {
+	var currentURL string
+	err := retry(ctx, policy, "Loading "+targetURL, slog.Default(), func() error {
+		return runWithTimeout(ctx, chromedp.Tasks{
+			runAndWaitReady(chromedp.Navigate(targetURL), skoolPageJS, time.Duration(waitTime)*time.Second),
+			chromedp.Location(&currentURL),
+		})
+	})
+	return currentURL, err
+}
+
+func navigateAndScrape(ctx context.Context, targetURL string, waitTime int, policy RetryPolicy) ([]VideoRef, error) {
+	slog.Info("🏫 Navigating to classroom", "url", targetURL)
+	traffic, stop := watchPageTraffic(ctx)
+	defer stop()
+	currentURL, err := navigate(ctx, targetURL, waitTime, policy)
+	if err != nil {
 		return nil, fmt.Errorf("failed to navigate to classroom: %v", err)
 	}
 
-	fmt.Println("📍 Landed on:", currentURL)
+	slog.Info("📍 Landed on", "url", currentURL)
 
 	// Check if we're on the right page
 	if strings.Contains(currentURL, "/about") {
-		return nil, fmt.Errorf("authentication succeeded but redirected to public page, check URL permissions")
+		return nil, errPublicPage
 	}
 
-	// Get page content
-	if err := chromedp.Run(ctx, chromedp.Tasks{
-		chromedp.OuterHTML("html", &html),
-	}); err != nil {
+	// Extract and return video URLs
+	videos, err := pageVideos(ctx, currentURL, traffic)
+	if err != nil {
 		return nil, err
 	}
-
-	// Extract and return video URLs
-	urls := extractLoomURLs(html)
-	if len(urls) == 0 {
-		fmt.Println("⚠️ No videos found on the page.")
+	if len(videos) == 0 {
+		slog.Warn("⚠️ No videos found on the page.")
 	}
 
-	return urls, nil
+	community := communitySlug(targetURL)
+	for i := range videos {
+		videos[i].Community = community
+	}
+	return videos, nil
 }
 
 // Cookie parsing functions
@@ -453,12 +820,40 @@ func parseInt64(s string) (int64, error) {
 	return result, err
 }
 
-func downloadWithYtDlp(videoURL, cookiesFile, outputDir string) error {
+// ytDlpArgs returns the yt-dlp arguments for downloading video, without the
+// cookies. yt-dlp reports the saved file's path to pathFile.
+func ytDlpArgs(video VideoRef, outputDir, pathFile string, newline bool) []string {
 	args := []string{
-		"-o", filepath.Join(outputDir, "%(title)s.%(ext)s"),
+		"--print-to-file", "after_move:filepath", pathFile,
+		"-o", outputTemplate(outputDir, video),
 		"--no-warnings",
-		videoURL,
 	}
+	if newline {
+		args = append([]string{"--newline"}, args...)
+	}
+	// Vimeo only plays domain-restricted embeds for the embedding site
+	if video.Provider == providerVimeo {
+		args = append(args, "--referer", skoolBaseURL)
+	}
+	return append(args, video.URL)
+}
+
+// downloadWithYtDlp runs yt-dlp for a single video, writing its output to
+// stdout and stderr, and returns the path of the saved file. With newline set,
+// progress is printed as separate lines instead of being redrawn in place. The
+// process is interrupted when ctx is cancelled.
+func downloadWithYtDlp(ctx context.Context, video VideoRef, cookiesFile, outputDir string, newline bool, stdout, stderr io.Writer) (string, error) {
+	// yt-dlp reports the final file name, after any merging, through a file
+	pathFile, err := os.CreateTemp("", "skool-loom-dl-path-*.txt")
+	if err != nil {
+		return "", err
+	}
+	_ = pathFile.Close()
+	defer func() {
+		_ = os.Remove(pathFile.Name())
+	}()
+
+	args := ytDlpArgs(video, outputDir, pathFile.Name(), newline)
 
 	// Only add cookies argument if a cookies file is provided
 	if cookiesFile != "" {
@@ -468,7 +863,7 @@ func downloadWithYtDlp(videoURL, cookiesFile, outputDir string) error {
 		if isJSON {
 			tmpFile, err := convertJSONToNetscapeCookies(cookiesFile)
 			if err != nil {
-				return fmt.Errorf("error converting JSON cookies: %v", err)
+				return "", fmt.Errorf("error converting JSON cookies: %v", err)
 			}
 			defer func() {
 				_ = os.Remove(tmpFile)
@@ -480,11 +875,34 @@ func downloadWithYtDlp(videoURL, cookiesFile, outputDir string) error {
 		args = append([]string{"--cookies", tmpCookiesFile}, args...)
 	}
 
-	cmd := exec.Command("yt-dlp", args...)
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
+	output := &tailBuffer{limit: 4096}
+	cmd := exec.CommandContext(ctx, "yt-dlp", args...)
+	cmd.Stdout = stdout
+	cmd.Stderr = io.MultiWriter(stderr, output)
 
-	return cmd.Run()
+	// Give yt-dlp the chance to clean up its partial files before killing it
+	cmd.Cancel = func() error {
+		if err := cmd.Process.Signal(os.Interrupt); err != nil {
+			return cmd.Process.Kill()
+		}
+		return nil
+	}
+	cmd.WaitDelay = processStopTimeout
+
+	err = cmd.Run()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && ctx.Err() == nil {
+		return "", &ytDlpError{ExitCode: exitErr.ExitCode(), Output: output.String()}
+	}
+	if err != nil {
+		return "", err
+	}
+
+	content, err := os.ReadFile(pathFile.Name())
+	if err != nil {
+		return "", nil
+	}
+	return strings.TrimSpace(string(content)), nil
 }
 
 func convertJSONToNetscapeCookies(jsonFile string) (string, error) {
@@ -1,76 +1,154 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"os"
 	"path/filepath"
 	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/chromedp"
 )
 
-func TestExtractLoomURLs(t *testing.T) {
+func TestExtractLoomVideos(t *testing.T) {
+	const page = "https://www.skool.com/school/classroom/abc?md=l1"
+	share := func(id, original string, index int) VideoRef {
+		return VideoRef{Provider: providerLoom, VideoID: id, URL: loomShareURL(id), OriginalURL: original, Form: videoFormShare, SourceURL: page, Index: index}
+	}
+	embed := func(id, original string, index int) VideoRef {
+		return VideoRef{Provider: providerLoom, VideoID: id, URL: loomShareURL(id), OriginalURL: original, Form: videoFormEmbed, SourceURL: page, Index: index}
+	}
+
 	tests := []struct {
 		name     string
 		html     string
-		expected []string
+		expected []VideoRef
 	}{
 		{
 			name:     "Empty HTML",
 			html:     "",
-			expected: []string{},
+			expected: []VideoRef{},
 		},
 		{
 			name:     "No Loom URLs",
 			html:     "<html><body>No videos here</body></html>",
-			expected: []string{},
+			expected: []VideoRef{},
 		},
 		{
 			name:     "Single share URL",
 			html:     `<html><body><a href="https://www.loom.com/share/abc123">Video</a></body></html>`,
-			expected: []string{"https://www.loom.com/share/abc123"},
+			expected: []VideoRef{share("abc123", "https://www.loom.com/share/abc123", 1)},
 		},
 		{
 			name:     "Single share URL without www",
 			html:     `<html><body><a href="https://loom.com/share/xyz789">Video</a></body></html>`,
-			expected: []string{"https://loom.com/share/xyz789"},
+			expected: []VideoRef{share("xyz789", "https://loom.com/share/xyz789", 1)},
 		},
 		{
 			name:     "Single embed URL",
 			html:     `<html><body><iframe src="https://www.loom.com/embed/def456"></iframe></body></html>`,
-			expected: []string{"https://www.loom.com/share/def456"},
+			expected: []VideoRef{embed("def456", "https://www.loom.com/embed/def456", 1)},
 		},
 		{
-			name:     "Multiple URLs",
-			html:     `<html><body><a href="https://www.loom.com/share/abc123">Video1</a><a href="https://loom.com/share/xyz789">Video2</a></body></html>`,
-			expected: []string{"https://www.loom.com/share/abc123", "https://loom.com/share/xyz789"},
+			name: "Multiple URLs",
+			html: `<html><body><a href="https://www.loom.com/share/abc123">Video1</a><a href="https://loom.com/share/xyz789">Video2</a></body></html>`,
+			expected: []VideoRef{
+				share("abc123", "https://www.loom.com/share/abc123", 1),
+				share("xyz789", "https://loom.com/share/xyz789", 2),
+			},
 		},
 		{
 			name:     "Duplicate URLs",
 			html:     `<html><body><a href="https://www.loom.com/share/abc123">Video1</a><a href="https://www.loom.com/share/abc123">Video2</a></body></html>`,
-			expected: []string{"https://www.loom.com/share/abc123"},
+			expected: []VideoRef{share("abc123", "https://www.loom.com/share/abc123", 1)},
 		},
 		{
-			name:     "Mix of share and embed URLs",
-			html:     `<html><body><a href="https://www.loom.com/share/abc123">Video1</a><iframe src="https://loom.com/embed/def456"></iframe></body></html>`,
-			expected: []string{"https://www.loom.com/share/abc123", "https://www.loom.com/share/def456"},
+			name: "Mix of share and embed URLs",
+			html: `<html><body><a href="https://www.loom.com/share/abc123">Video1</a><iframe src="https://loom.com/embed/def456"></iframe></body></html>`,
+			expected: []VideoRef{
+				share("abc123", "https://www.loom.com/share/abc123", 1),
+				embed("def456", "https://loom.com/embed/def456", 2),
+			},
 		},
 		{
 			name:     "Embed and share of same video",
 			html:     `<html><body><a href="https://www.loom.com/share/abc123">Video1</a><iframe src="https://loom.com/embed/abc123"></iframe></body></html>`,
-			expected: []string{"https://www.loom.com/share/abc123"},
+			expected: []VideoRef{share("abc123", "https://www.loom.com/share/abc123", 1)},
+		},
+		{
+			name:     "Embed before share of same video",
+			html:     `<html><body><iframe src="https://loom.com/embed/abc123"></iframe><a href="https://www.loom.com/share/abc123">Video1</a></body></html>`,
+			expected: []VideoRef{embed("abc123", "https://loom.com/embed/abc123", 1)},
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			result := extractLoomURLs(tt.html)
+			result := extractVideos(tt.html, page)
 			// Handle nil vs empty slice comparison
 			if len(result) == 0 && len(tt.expected) == 0 {
 				return
 			}
 			if !reflect.DeepEqual(result, tt.expected) {
-				t.Errorf("extractLoomURLs() = %v, want %v", result, tt.expected)
+				t.Errorf("extractVideos() = %v, want %v", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestUniqueVideos(t *testing.T) {
+	videos := []VideoRef{
+		{Provider: providerLoom, VideoID: "a", LessonTitle: "First"},
+		{Provider: providerLoom, VideoID: "b", LessonTitle: "First"},
+		{Provider: providerLoom, VideoID: "a", LessonTitle: "Second"},
+	}
+
+	expected := []VideoRef{
+		{Provider: providerLoom, VideoID: "a", LessonTitle: "First"},
+		{Provider: providerLoom, VideoID: "b", LessonTitle: "First"},
+	}
+
+	result := uniqueVideos(videos)
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("uniqueVideos() = %v, want %v", result, expected)
+	}
+}
+
+func TestRunWithTimeout(t *testing.T) {
+	// Without a browser in ctx, chromedp refuses to run the action
+	ran := false
+	err := runWithTimeout(context.Background(), chromedp.ActionFunc(func(context.Context) error {
+		ran = true
+		return nil
+	}))
+	if !errors.Is(err, chromedp.ErrInvalidContext) || ran {
+		t.Errorf("runWithTimeout() error = %v, want chromedp.ErrInvalidContext", err)
+	}
+}
+
+func TestYtDlpArgs(t *testing.T) {
+	tests := []struct {
+		name    string
+		video   VideoRef
+		referer bool
+	}{
+		{name: "Vimeo player", video: VideoRef{Provider: providerVimeo, VideoID: "1", URL: "https://player.vimeo.com/video/1?h=abc"}, referer: true},
+		{name: "Vimeo page", video: VideoRef{Provider: providerVimeo, VideoID: "1", URL: "https://vimeo.com/1/abc"}, referer: true},
+		{name: "Loom", video: loomRef("abc123"), referer: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args := ytDlpArgs(tt.video, "out", "path.txt", false)
+			if args[len(args)-1] != tt.video.URL {
+				t.Errorf("Expected the video URL last, got %q", args)
+			}
+			referer := strings.Contains(strings.Join(args, " "), "--referer "+skoolBaseURL)
+			if referer != tt.referer {
+				t.Errorf("ytDlpArgs() = %q, want Skool referer %v", args, tt.referer)
 			}
 		})
 	}
@@ -456,15 +534,100 @@ func TestParseCookiesFile_NonexistentFile(t *testing.T) {
 }
 
 func TestValidateConfig_NoURL(t *testing.T) {
-	// This test will cause os.Exit(1), so we skip it in normal test runs
-	// It's documented here for completeness
-	t.Skip("Skipping test that calls os.Exit")
+	err := validateConfig(Config{CookiesFile: "cookies.json"})
+	if !errors.Is(err, errUsage) {
+		t.Errorf("Expected usage error, got %v", err)
+	}
 }
 
 func TestValidateConfig_NoAuth(t *testing.T) {
-	// This test will cause os.Exit(1), so we skip it in normal test runs
-	// It's documented here for completeness
-	t.Skip("Skipping test that calls os.Exit")
+	err := validateConfig(Config{SkoolURL: "https://www.skool.com/school/classroom"})
+	if err == nil {
+		t.Error("Expected error for missing authentication, got nil")
+	}
+}
+
+func TestValidateConfig_Modes(t *testing.T) {
+	tests := []struct {
+		name      string
+		config    Config
+		shouldErr bool
+	}{
+		{
+			name:   "Scrape with cookies",
+			config: Config{SkoolURL: "https://www.skool.com/school/classroom", CookiesFile: "cookies.json"},
+		},
+		{
+			name:   "Scrape with email and password",
+			config: Config{SkoolURL: "https://www.skool.com/school/classroom", Email: "a@b.c", Password: "pw"},
+		},
+		{
+			name:      "Email without password",
+			config:    Config{SkoolURL: "https://www.skool.com/school/classroom", Email: "a@b.c"},
+			shouldErr: true,
+		},
+		{
+			name:   "Email without password on a terminal",
+			config: Config{SkoolURL: "https://www.skool.com/school/classroom", Email: "a@b.c", Interactive: true},
+		},
+		{
+			name:   "Email with password file",
+			config: Config{SkoolURL: "https://www.skool.com/school/classroom", Email: "a@b.c", PasswordFile: "password.txt"},
+		},
+		{
+			name:   "Email with password on stdin",
+			config: Config{SkoolURL: "https://www.skool.com/school/classroom", Email: "a@b.c", PasswordStdin: true},
+		},
+		{
+			name:   "Check cookies without URL",
+			config: Config{CheckCookies: true, CookiesFile: "cookies.json"},
+		},
+		{
+			name:      "Check cookies without cookies file",
+			config:    Config{CheckCookies: true, SkoolURL: "https://www.skool.com/school/classroom"},
+			shouldErr: true,
+		},
+		{
+			name:   "Download from list without credentials",
+			config: Config{From: "videos.txt"},
+		},
+		{
+			name:   "Download from list with cookies",
+			config: Config{From: "videos.txt", CookiesFile: "cookies.json"},
+		},
+		{
+			name:      "URL and list together",
+			config:    Config{From: "videos.txt", SkoolURL: "https://www.skool.com/school/classroom"},
+			shouldErr: true,
+		},
+		{
+			name:      "Unknown export format",
+			config:    Config{SkoolURL: "https://www.skool.com/school/classroom", CookiesFile: "cookies.json", Export: "xml"},
+			shouldErr: true,
+		},
+		{
+			name:      "Unknown downloader",
+			config:    Config{From: "videos.txt", Downloader: "wget"},
+			shouldErr: true,
+		},
+		{
+			name:      "Retry jitter out of range",
+			config:    Config{From: "videos.txt", RetryJitter: 1.5},
+			shouldErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateConfig(tt.config)
+			if tt.shouldErr && err == nil {
+				t.Error("Expected error, got nil")
+			}
+			if !tt.shouldErr && err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+		})
+	}
 }
 
 // Helper function
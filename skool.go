@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/chromedp/cdproto/network"
+)
+
+// skoolVideoHosts are the domains Skool's player streams uploaded videos
+// from. Skool hosts its uploads with Mux.
+var skoolVideoHosts = []string{"skool.com", "stream.mux.com"}
+
+// identifySkoolVideo identifies the video played from an HLS playlist URL on
+// one of Skool's video hosts. The ID is derived from the playlist's host and
+// path, which stay the same when its access token is renewed.
+func identifySkoolVideo(match string) (id, videoURL string, ok bool) {
+	videoURL = html.UnescapeString(match)
+	if !isSkoolPlaylistURL(videoURL) {
+		return "", "", false
+	}
+	u, err := url.Parse(videoURL)
+	if err != nil {
+		return "", "", false
+	}
+	sum := sha256.Sum256([]byte(u.Host + u.Path))
+	return hex.EncodeToString(sum[:6]), videoURL, true
+}
+
+// isHLSPlaylistURL reports whether raw points at an HLS playlist
+func isHLSPlaylistURL(raw string) bool {
+	u, err := url.Parse(raw)
+	return err == nil && strings.HasSuffix(strings.ToLower(u.Path), ".m3u8")
+}
+
+// isSkoolPlaylistURL reports whether raw points at an HLS playlist on one of
+// Skool's video hosts
+func isSkoolPlaylistURL(raw string) bool {
+	u, err := url.Parse(raw)
+	if err != nil || !isHLSPlaylistURL(raw) {
+		return false
+	}
+	host := u.Hostname()
+	for _, domain := range skoolVideoHosts {
+		if host == domain || strings.HasSuffix(host, "."+domain) {
+			return true
+		}
+	}
+	return false
+}
+
+// skoolVideoTitle returns the title a video uploaded to Skool is saved under.
+// Its playlist carries no title, so the lesson's is used by every backend.
+func skoolVideoTitle(video VideoRef) string {
+	if video.LessonTitle != "" {
+		return video.LessonTitle
+	}
+	return "Skool video"
+}
+
+// skoolClient fetches videos uploaded to Skool. The playlists and segments
+// are requested with the session's cookies for their host and Skool as the
+// referrer, as the browser's player does.
+type skoolClient struct {
+	httpClient *http.Client
+	cookies    []*network.CookieParam
+}
+
+func newSkoolClient(cookies []*network.CookieParam) *skoolClient {
+	return &skoolClient{httpClient: newMediaHTTPClient(), cookies: cookies}
+}
+
+// cookieHeader builds a Cookie header from the cookies set for host
+func (c *skoolClient) cookieHeader(host string) string {
+	var parts []string
+	for _, cookie := range c.cookies {
+		domain := strings.TrimPrefix(cookie.Domain, ".")
+		if domain != "" && (host == domain || strings.HasSuffix(host, "."+domain)) {
+			parts = append(parts, cookie.Name+"="+cookie.Value)
+		}
+	}
+	return strings.Join(parts, "; ")
+}
+
+func (c *skoolClient) get(ctx context.Context, mediaURL string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, mediaURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Referer", skoolBaseURL)
+	if header := c.cookieHeader(req.URL.Hostname()); header != "" {
+		req.Header.Set("Cookie", header)
+	}
+	return sendRequest(c.httpClient, req)
+}
+
+// downloadSkoolVideo downloads a video uploaded to Skool from its HLS
+// playlist and returns the path of the saved file
+func downloadSkoolVideo(ctx context.Context, client *skoolClient, video VideoRef, outputDir string, out io.Writer) (string, error) {
+	title := skoolVideoTitle(video)
+	_, _ = fmt.Fprintf(out, "[native] Downloading HLS stream: %s\n", title)
+	return downloadHLS(ctx, client, video.URL, func(ext string) string {
+		return nativeOutputPath(outputDir, video, title, ext)
+	}, out)
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/chromedp/cdproto/network"
+)
+
+func TestExtractVideos_SkoolPlaylists(t *testing.T) {
+	html := `<video src="https://stream.video.skool.com/abc/master.m3u8?token=x&amp;exp=1"></video>
+		<script>{"src":"https://stream.video.skool.com/abc/master.m3u8?token=y"}</script>
+		<a href="https://luna.loom.com/id/abc123/rev/1/resource/hls/playlist.m3u8">Loom stream</a>
+		<a href="https://skyfire.vimeocdn.com/1/playlist.m3u8">Vimeo stream</a>
+		<a href="https://manifest.googlevideo.com/api/manifest/hls_variant/id/1/file/index.m3u8">YouTube stream</a>
+		<a href="https://cdn.example.com/course/intro.m3u8">Other player</a>
+		<a href="https://stream.mux.com/Zx2Y3w.m3u8?token=z">`
+
+	videos := extractVideos(html, "https://www.skool.com/school/classroom/c")
+	if len(videos) != 2 {
+		t.Fatalf("extractVideos() found %d videos, want 2: %+v", len(videos), videos)
+	}
+
+	first := videos[0]
+	if first.Provider != providerSkool || first.Form != videoFormEmbed {
+		t.Errorf("extractVideos() = %+v, want an embedded Skool video", first)
+	}
+	if first.URL != "https://stream.video.skool.com/abc/master.m3u8?token=x&exp=1" {
+		t.Errorf("URL = %q, want the unescaped playlist URL", first.URL)
+	}
+	if videos[1].Provider != providerSkool || videos[1].URL != "https://stream.mux.com/Zx2Y3w.m3u8?token=z" {
+		t.Errorf("Expected the Mux stream as second Skool video, got %+v", videos[1])
+	}
+}
+
+// fakeSkoolCDN serves files and records the headers of each request
+type fakeSkoolCDN struct {
+	server *httptest.Server
+	files  map[string]string
+
+	mu       sync.Mutex
+	cookies  []string
+	referers []string
+}
+
+func newFakeSkoolCDN(t *testing.T) *fakeSkoolCDN {
+	f := &fakeSkoolCDN{files: make(map[string]string)}
+	f.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		f.mu.Lock()
+		f.cookies = append(f.cookies, r.Header.Get("Cookie"))
+		f.referers = append(f.referers, r.Header.Get("Referer"))
+		f.mu.Unlock()
+
+		content, ok := f.files[strings.TrimPrefix(r.URL.Path, "/")]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = io.WriteString(w, content)
+	}))
+	t.Cleanup(f.server.Close)
+	return f
+}
+
+func TestDownloadSkoolVideo(t *testing.T) {
+	t.Setenv("PATH", t.TempDir()) // no ffmpeg, keep the MPEG-TS stream
+	cdn := newFakeSkoolCDN(t)
+	cdn.files["abc/master.m3u8"] = "#EXTM3U\n#EXT-X-TARGETDURATION:4\n" +
+		"#EXTINF:4.0,\nseg0.ts\n#EXTINF:4.0,\nseg1.ts\n#EXT-X-ENDLIST\n"
+	cdn.files["abc/seg0.ts"] = "first-"
+	cdn.files["abc/seg1.ts"] = "second"
+
+	client := newSkoolClient([]*network.CookieParam{
+		{Name: "auth_token", Value: "s1", Domain: "127.0.0.1"},
+		{Name: "connect.sid", Value: "l1", Domain: ".loom.com"},
+	})
+	video := VideoRef{Provider: providerSkool, VideoID: "0123456789ab", URL: cdn.server.URL + "/abc/master.m3u8?token=x"}
+
+	outputDir := t.TempDir()
+	path, err := downloadSkoolVideo(context.Background(), client, video, outputDir, io.Discard)
+	if err != nil {
+		t.Fatalf("downloadSkoolVideo() error = %v", err)
+	}
+
+	if expected := filepath.Join(outputDir, "Skool video [0123456789ab].ts"); path != expected {
+		t.Errorf("downloadSkoolVideo() path = %q, want %q", path, expected)
+	}
+	assertFileContent(t, path, "first-second")
+
+	for i, cookie := range cdn.cookies {
+		if cookie != "auth_token=s1" || cdn.referers[i] != skoolBaseURL {
+			t.Errorf("Request %d sent Cookie %q and Referer %q, want only the CDN's cookies and Skool as referrer", i, cookie, cdn.referers[i])
+		}
+	}
+}
+
+func TestSkoolVideoNames(t *testing.T) {
+	first := VideoRef{Provider: providerSkool, VideoID: "abc", Community: "school", Course: "Course", LessonTitle: "Intro", LessonIndex: 1}
+	second := first
+	second.VideoID = "def"
+
+	// yt-dlp and the native download agree on the name, which keeps the
+	// videos of one lesson apart
+	native := nativeOutputPath("out", first, skoolVideoTitle(first), "mp4")
+	template := strings.Replace(outputTemplate("out", first), "%(ext)s", "mp4", 1)
+	if native != template || native != filepath.Join("out", "school", "Course", "01 - Intro - Intro [abc].mp4") {
+		t.Errorf("native path %q and yt-dlp template %q differ", native, template)
+	}
+	if outputTemplate("out", first) == outputTemplate("out", second) {
+		t.Error("Expected different yt-dlp templates for videos of one lesson")
+	}
+}
+
+func TestDownloadSkoolVideo_Expired(t *testing.T) {
+	cdn := newFakeSkoolCDN(t)
+	video := VideoRef{Provider: providerSkool, VideoID: "0123456789ab", URL: cdn.server.URL + "/abc/master.m3u8?token=old"}
+
+	_, err := downloadSkoolVideo(context.Background(), newSkoolClient(nil), video, t.TempDir(), io.Discard)
+	if err == nil {
+		t.Fatal("Expected error for a missing playlist, got nil")
+	}
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+// Exit codes, so scripts can tell a broken run from a good one
+const (
+	exitOK             = 0
+	exitError          = 1 // invalid flags or an unexpected error
+	exitAuthFailed     = 2
+	exitNothingFound   = 3
+	exitPartialFailure = 4 // some videos failed to download
+	exitTotalFailure   = 5 // no video could be downloaded
+	exitInterrupted    = 130
+)
+
+// errAuthFailed is wrapped by errors caused by rejected credentials or an
+// account without access to the requested page
+var errAuthFailed = errors.New("authentication failed")
+
+// errPublicPage is returned when Skool redirects to the public about page,
+// which happens when the session is not a member of the community
+var errPublicPage = permanent(fmt.Errorf("%w: redirected to public page, check URL permissions", errAuthFailed))
+
+// Run states written to the summary file, one per exit code
+var runStatuses = map[int]string{
+	exitOK:             "ok",
+	exitError:          "error",
+	exitAuthFailed:     "auth_failed",
+	exitNothingFound:   "nothing_found",
+	exitPartialFailure: "partial_failure",
+	exitTotalFailure:   "failed",
+	exitInterrupted:    "interrupted",
+}
+
+// exitCodeForError returns the exit code for a run that stopped with err
+func exitCodeForError(err error) int {
+	if errors.Is(err, errAuthFailed) {
+		return exitAuthFailed
+	}
+	return exitError
+}
+
+// exitCodeForResults returns the exit code for a run that downloaded the
+// given videos. Skipped videos count as successes, since they were downloaded
+// by an earlier run.
+func exitCodeForResults(results []downloadResult, interrupted bool) int {
+	if interrupted {
+		return exitInterrupted
+	}
+
+	failed := 0
+	for _, result := range results {
+		if result.Status == statusFailed {
+			failed++
+		}
+	}
+	switch {
+	case failed == 0:
+		return exitOK
+	case failed == len(results):
+		return exitTotalFailure
+	default:
+		return exitPartialFailure
+	}
+}
+
+// runSummary is the document written by -summary
+type runSummary struct {
+	Status        string         `json:"status"`
+	ExitCode      int            `json:"exit_code"`
+	Error         string         `json:"error,omitempty"`
+	Source        string         `json:"source"`
+	StartedAt     time.Time      `json:"started_at"`
+	FinishedAt    time.Time      `json:"finished_at"`
+	Downloaded    int            `json:"downloaded"`
+	WouldDownload int            `json:"would_download,omitempty"`
+	Skipped       int            `json:"skipped"`
+	Failed        int            `json:"failed"`
+	Videos        []videoSummary `json:"videos"`
+}
+
+// videoSummary is the outcome of one video in the summary file
+type videoSummary struct {
+	Provider string  `json:"provider"`
+	VideoID  string  `json:"video_id"`
+	URL      string  `json:"url"`
+	Lesson   string  `json:"lesson,omitempty"`
+	Status   string  `json:"status"`
+	Path     string  `json:"path,omitempty"`
+	Bytes    int64   `json:"bytes,omitempty"`
+	Duration float64 `json:"duration_seconds"`
+	Error    string  `json:"error,omitempty"`
+}
+
+// newRunSummary describes a finished run. runErr is the error that stopped
+// the run early, if any.
+func newRunSummary(source string, startedAt time.Time, exitCode int, runErr error, results []downloadResult) runSummary {
+	summary := runSummary{
+		Status:     runStatuses[exitCode],
+		ExitCode:   exitCode,
+		Source:     source,
+		StartedAt:  startedAt,
+		FinishedAt: time.Now(),
+		Videos:     []videoSummary{},
+	}
+	if runErr != nil {
+		summary.Error = runErr.Error()
+	}
+
+	for _, result := range results {
+		video := videoSummary{
+			Provider: result.Video.Provider,
+			VideoID:  result.Video.VideoID,
+			URL:      result.Video.URL,
+			Lesson:   videoLessonLabel(result.Video),
+			Status:   result.Status,
+			Path:     result.Path,
+			Bytes:    result.Bytes,
+			Duration: result.Duration.Seconds(),
+		}
+		if result.Err != nil {
+			video.Error = result.Err.Error()
+		}
+		summary.Videos = append(summary.Videos, video)
+
+		switch result.Status {
+		case statusDownloaded:
+			summary.Downloaded++
+		case statusWouldDownload:
+			summary.WouldDownload++
+		case statusSkipped:
+			summary.Skipped++
+		case statusFailed:
+			summary.Failed++
+		}
+	}
+	return summary
+}
+
+// writeSummary writes the run summary as JSON to path
+func writeSummary(path string, summary runSummary) error {
+	content, err := json.MarshalIndent(summary, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(content, '\n'), 0644)
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestExitCodeForResults(t *testing.T) {
+	downloaded := downloadResult{Status: statusDownloaded}
+	skipped := downloadResult{Status: statusSkipped}
+	failed := downloadResult{Status: statusFailed, Err: errors.New("boom")}
+
+	tests := []struct {
+		name        string
+		results     []downloadResult
+		interrupted bool
+		expected    int
+	}{
+		{name: "all downloaded", results: []downloadResult{downloaded, skipped}, expected: exitOK},
+		{name: "some failed", results: []downloadResult{downloaded, failed}, expected: exitPartialFailure},
+		{name: "some failed after skips", results: []downloadResult{skipped, failed}, expected: exitPartialFailure},
+		{name: "all failed", results: []downloadResult{failed, failed}, expected: exitTotalFailure},
+		{name: "interrupted", results: []downloadResult{downloaded, failed}, interrupted: true, expected: exitInterrupted},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := exitCodeForResults(tt.results, tt.interrupted); got != tt.expected {
+				t.Errorf("exitCodeForResults() = %d, want %d", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestExitCodeForError(t *testing.T) {
+	tests := []struct {
+		err      error
+		expected int
+	}{
+		{err: errPublicPage, expected: exitAuthFailed},
+		{err: fmt.Errorf("login: %w", permanent(fmt.Errorf("%w: invalid credentials", errAuthFailed))), expected: exitAuthFailed},
+		{err: errors.New("failed to start browser"), expected: exitError},
+	}
+
+	for _, tt := range tests {
+		if got := exitCodeForError(tt.err); got != tt.expected {
+			t.Errorf("exitCodeForError(%v) = %d, want %d", tt.err, got, tt.expected)
+		}
+	}
+}
+
+func TestWriteSummary(t *testing.T) {
+	results := []downloadResult{
+		{
+			Video:    VideoRef{Provider: providerLoom, VideoID: "a", URL: loomShareURL("a"), Course: "Course", LessonTitle: "Intro"},
+			Status:   statusDownloaded,
+			Path:     "downloads/Course/01 - Intro - Video.mp4",
+			Bytes:    2048,
+			Duration: 1500 * time.Millisecond,
+		},
+		{Video: VideoRef{Provider: providerYouTube, VideoID: "b", URL: "https://www.youtube.com/watch?v=b"}, Status: statusSkipped},
+		{Video: VideoRef{Provider: providerLoom, VideoID: "c", URL: loomShareURL("c")}, Status: statusFailed, Err: errors.New("HTTP 404")},
+	}
+
+	path := filepath.Join(t.TempDir(), "summary.json")
+	summary := newRunSummary("videos.txt", time.Now(), exitPartialFailure, nil, results)
+	if err := writeSummary(path, summary); err != nil {
+		t.Fatalf("writeSummary() error = %v", err)
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read summary: %v", err)
+	}
+	var got runSummary
+	if err := json.Unmarshal(content, &got); err != nil {
+		t.Fatalf("Summary is not valid JSON: %v", err)
+	}
+
+	if got.Status != "partial_failure" || got.ExitCode != exitPartialFailure {
+		t.Errorf("Unexpected status %q with exit code %d", got.Status, got.ExitCode)
+	}
+	if got.Downloaded != 1 || got.Skipped != 1 || got.Failed != 1 {
+		t.Errorf("Unexpected counts: downloaded %d, skipped %d, failed %d", got.Downloaded, got.Skipped, got.Failed)
+	}
+	if len(got.Videos) != 3 {
+		t.Fatalf("Expected 3 videos, got %d", len(got.Videos))
+	}
+
+	first := got.Videos[0]
+	if first.Path != results[0].Path || first.Bytes != 2048 || first.Duration != 1.5 || first.Lesson != "Course › Intro" {
+		t.Errorf("Unexpected first video: %+v", first)
+	}
+	if first.Provider != providerLoom || first.VideoID != "a" || got.Videos[1].Provider != providerYouTube {
+		t.Errorf("Unexpected video hosts: %q, %q", first.Provider, got.Videos[1].Provider)
+	}
+	if got.Videos[2].Error != "HTTP 404" {
+		t.Errorf("Expected error of failed video, got %q", got.Videos[2].Error)
+	}
+}
+
+func TestNewRunSummary_DryRun(t *testing.T) {
+	results := []downloadResult{
+		{Video: loomRef("a"), Status: statusWouldDownload, Path: "downloads/a.mp4"},
+		{Video: loomRef("b"), Status: statusSkipped},
+	}
+	summary := newRunSummary("videos.txt", time.Now(), exitOK, nil, results)
+	if summary.Downloaded != 0 || summary.WouldDownload != 1 || summary.Skipped != 1 {
+		t.Errorf("Unexpected counts: downloaded %d, would download %d, skipped %d", summary.Downloaded, summary.WouldDownload, summary.Skipped)
+	}
+	if summary.Videos[0].Status != statusWouldDownload {
+		t.Errorf("Status = %q, want %q", summary.Videos[0].Status, statusWouldDownload)
+	}
+}
+
+func TestNewRunSummary_RunError(t *testing.T) {
+	summary := newRunSummary("https://www.skool.com/school/classroom", time.Now(), exitAuthFailed, errPublicPage, nil)
+	if summary.Status != "auth_failed" || summary.Error == "" {
+		t.Errorf("Expected auth_failed status with an error, got %+v", summary)
+	}
+	if summary.Videos == nil {
+		t.Error("Expected an empty video list rather than null")
+	}
+}